@@ -0,0 +1,74 @@
+package ko
+
+import (
+	"reflect"
+)
+
+type (
+	// Alloc is an option for Load method which makes Load allocate every nil
+	// pointer-to-struct field the same way as alloc:"true" tag does. Fields
+	// can opt out with alloc:"false".
+	Alloc bool
+)
+
+// shouldAllocate reports whether the field is a nil pointer to struct which
+// has to be allocated to apply defaults and env values of its fields.
+func (loader *loader) shouldAllocate(
+	field reflect.Value,
+	structField reflect.StructField,
+) bool {
+	if field.Kind() != reflect.Ptr || !field.IsNil() || !field.CanSet() ||
+		field.Type().Elem().Kind() != reflect.Struct {
+		return false
+	}
+
+	// Members of oneof groups are chosen by presence, allocating them
+	// would make them all chosen.
+	if structField.Tag.Get("oneof_group") != "" {
+		return false
+	}
+
+	// Types which are already being allocated up the stack are skipped,
+	// otherwise self-referencing types would be allocated forever.
+	if inSlice(loader.allocating, field.Type().Elem()) {
+		return false
+	}
+
+	switch structField.Tag.Get("alloc") {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+
+	return loader.alloc
+}
+
+// allocate validates a newly allocated struct for the nil pointer field
+// and stores it in the field only if anything in it ended up set, so
+// sections without defaults, env values or file values stay nil.
+func (loader *loader) allocate(
+	field reflect.Value,
+	required bool,
+	prefix []string,
+) (bool, error) {
+	target := field.Type().Elem()
+
+	loader.allocating = push(loader.allocating, target)
+	allocated := reflect.New(target)
+
+	err := loader.validate(allocated.Interface(), required, prefix...)
+
+	loader.allocating = loader.allocating[:len(loader.allocating)-1]
+	if err != nil {
+		return false, err
+	}
+
+	if allocated.Elem().IsZero() {
+		return false, nil
+	}
+
+	field.Set(allocated)
+
+	return true, nil
+}
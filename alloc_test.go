@@ -0,0 +1,113 @@
+package ko
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type allocTLS struct {
+	Cert    string `yaml:"cert"`
+	Version string `yaml:"version" default:"1.3"`
+}
+
+type allocAuth struct {
+	Token string `yaml:"token"`
+}
+
+func TestAlloc_Tag(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`name: main`)
+	defer os.Remove(path)
+
+	var cfg struct {
+		Name string     `yaml:"name"`
+		TLS  *allocTLS  `yaml:"tls" alloc:"true"`
+		Auth *allocAuth `yaml:"auth" alloc:"true"`
+		Next *allocTLS  `yaml:"next"`
+	}
+
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+	test.Equal(&allocTLS{Version: "1.3"}, cfg.TLS)
+	test.Nil(cfg.Auth)
+	test.Nil(cfg.Next)
+}
+
+func TestAlloc_Option(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`name: main`)
+	defer os.Remove(path)
+
+	var cfg struct {
+		Name string    `yaml:"name"`
+		TLS  *allocTLS `yaml:"tls"`
+		Next *allocTLS `yaml:"next" alloc:"false"`
+	}
+
+	err := Load(path, &cfg, yaml.Unmarshal, Alloc(true))
+	test.NoError(err)
+	test.Equal(&allocTLS{Version: "1.3"}, cfg.TLS)
+	test.Nil(cfg.Next)
+}
+
+func TestAlloc_Env(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(``)
+	defer os.Remove(path)
+
+	os.Setenv("KO_TEST_AUTH_TOKEN", "secret")
+	defer os.Unsetenv("KO_TEST_AUTH_TOKEN")
+
+	var cfg struct {
+		Auth *struct {
+			Token string `yaml:"token" env:"KO_TEST_AUTH_TOKEN"`
+		} `yaml:"auth" alloc:"true"`
+	}
+
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+	test.Equal("secret", cfg.Auth.Token)
+}
+
+func TestAlloc_Required(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(``)
+	defer os.Remove(path)
+
+	var cfg struct {
+		TLS *struct {
+			Cert    string `yaml:"cert" required:"true"`
+			Version string `yaml:"version" default:"1.3"`
+		} `yaml:"tls" alloc:"true" required:"true"`
+	}
+
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.EqualError(err, `field "tls.cert" is required, but no value specified`)
+}
+
+type allocNode struct {
+	Name string     `yaml:"name" default:"node"`
+	Next *allocNode `yaml:"next"`
+}
+
+func TestAlloc_SelfReference(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(``)
+	defer os.Remove(path)
+
+	var cfg struct {
+		Root *allocNode `yaml:"root"`
+	}
+
+	err := Load(path, &cfg, yaml.Unmarshal, Alloc(true))
+	test.NoError(err)
+	test.Equal(&allocNode{Name: "node"}, cfg.Root)
+}
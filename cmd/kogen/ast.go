@@ -28,9 +28,49 @@ func astPath(field *ast.Field) string {
 }
 
 func astFieldName(field *ast.Field) string {
+	if len(field.Names) == 0 {
+		if ident, ok := field.Type.(*ast.Ident); ok {
+			return ident.Name
+		}
+
+		return ""
+	}
+
 	return field.Names[0].Name
 }
 
+// astInline reports whether fields of the struct field are promoted to the
+// level of the struct containing it: embedded structs without a key name and
+// fields with yaml:",inline".
+func astInline(field *ast.Field) bool {
+	tags := map[string]string{}
+	if field.Tag != nil {
+		tags = astTags(field.Tag.Value)
+	}
+
+	for _, tag := range []string{"yaml", "toml", "json"} {
+		parts := strings.Split(tags[tag], ",")
+		if inSlice(parts[1:], "inline") {
+			return true
+		}
+
+		if parts[0] != "" {
+			return false
+		}
+	}
+
+	return len(field.Names) == 0
+}
+
+// astStack returns the path prefix for fields of the struct field.
+func astStack(field *ast.Field, stack []string) []string {
+	if astInline(field) {
+		return stack
+	}
+
+	return push(stack, astPath(field))
+}
+
 func astTag(tags map[string]string, tag string, defaultValue string) string {
 	if value, ok := tags[tag]; ok {
 		return value
@@ -38,6 +78,67 @@ func astTag(tags map[string]string, tag string, defaultValue string) string {
 	return defaultValue
 }
 
+// reservedDefaultTags are tags with default_ prefix which are not
+// default_<profile> tags, they must match the list in ko.
+var reservedDefaultTags = map[string]bool{
+	"default_keys":  true,
+	"default_merge": true,
+}
+
+// astProfileDefaults returns values of default_<profile> tags keyed by
+// profile name.
+func astProfileDefaults(tags map[string]string) map[string]string {
+	var defaults map[string]string
+	for key, value := range tags {
+		profile := strings.TrimPrefix(key, "default_")
+		if profile == key || profile == "" || reservedDefaultTags[key] {
+			continue
+		}
+
+		if defaults == nil {
+			defaults = map[string]string{}
+		}
+
+		defaults[profile] = value
+	}
+
+	return defaults
+}
+
+// networkValidators are values of validate tag checked by ko, the tag may
+// also carry rules of other libraries.
+var networkValidators = []string{"url", "hostport", "ip", "cidr", "port"}
+
+// astConstraints describes values accepted by validate and url_scheme tags,
+// e.g. "url (https, grpc), port".
+func astConstraints(tags map[string]string) string {
+	validators := []string{}
+	for _, validator := range strings.Split(tags["validate"], ",") {
+		validator = strings.TrimSpace(validator)
+		if inSlice(networkValidators, validator) {
+			validators = append(validators, validator)
+		}
+	}
+
+	schemes := tags["url_scheme"]
+	if schemes == "" {
+		return strings.Join(validators, ", ")
+	}
+
+	if !inSlice(validators, "url") {
+		validators = append([]string{"url"}, validators...)
+	}
+
+	for i, validator := range validators {
+		if validator == "url" {
+			validators[i] = "url (" +
+				strings.Join(strings.Split(schemes, ","), ", ") + ")"
+		}
+	}
+
+	return strings.Join(validators, ", ")
+}
+
 func astTags(line string) map[string]string {
 	line = strings.Trim(line, "`")
 
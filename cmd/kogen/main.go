@@ -71,6 +71,7 @@ func main() {
 	}
 
 	fields := generator.generate(target)
+	profiles := getProfiles(fields)
 
 	if args["--json"].(bool) {
 		encoder := json.NewEncoder(os.Stdout)
@@ -94,7 +95,10 @@ func main() {
 		},
 	})
 	tpl = template.Must(tpl.Parse(templateDocumentation))
-	err = tpl.Execute(os.Stdout, map[string][]StructField{"Fields": fields})
+	err = tpl.Execute(os.Stdout, map[string]interface{}{
+		"Fields":   fields,
+		"Profiles": profiles,
+	})
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -3,16 +3,19 @@ package main
 import (
 	"go/ast"
 	"go/token"
+	"sort"
 	"strings"
 )
 
 type StructField struct {
-	Name         string `json:"name"`
-	Type         string `json:"type"`
-	Path         string `json:"path"`
-	DefaultValue string `json:"default_value"`
-	Env          string `json:"env"`
-	Required     string `json:"required"`
+	Name            string            `json:"name"`
+	Type            string            `json:"type"`
+	Path            string            `json:"path"`
+	DefaultValue    string            `json:"default_value"`
+	ProfileDefaults map[string]string `json:"profile_defaults,omitempty"`
+	Env             string            `json:"env"`
+	Required        string            `json:"required"`
+	Constraints     string            `json:"constraints,omitempty"`
 }
 
 type Struct struct {
@@ -35,7 +38,7 @@ func (generator *Generator) generate(target *Struct, stack ...string) []StructFi
 					result,
 					generator.generate(
 						generator.structs[fieldType.Name],
-						push(stack, astPath(field))...,
+						astStack(field, stack)...,
 					)...,
 				)
 			} else {
@@ -52,7 +55,7 @@ func (generator *Generator) generate(target *Struct, stack ...string) []StructFi
 					&Struct{
 						Fields: fieldType.Fields.List,
 					},
-					push(stack, astPath(field))...,
+					astStack(field, stack)...,
 				)...,
 			)
 		}
@@ -70,15 +73,51 @@ func (generator *Generator) getField(
 	tags := astTags(field.Tag.Value)
 
 	return StructField{
-		Name:         fieldName,
-		Type:         fieldType.Name,
-		Path:         strings.Join(push(stack, astPath(field)), "."),
-		DefaultValue: astTag(tags, "default", ""),
-		Required:     astTag(tags, "required", "false"),
-		Env:          astTag(tags, "env", ""),
+		Name:            fieldName,
+		Type:            fieldType.Name,
+		Path:            strings.Join(push(stack, astPath(field)), "."),
+		DefaultValue:    astTag(tags, "default", ""),
+		ProfileDefaults: astProfileDefaults(tags),
+		Required:        astTag(tags, "required", "false"),
+		Env:             astTag(tags, "env", ""),
+		Constraints:     astConstraints(tags),
 	}
 }
 
+// getProfiles returns sorted names of profiles that have profile-specific
+// default values in any of the fields and fills default values of other
+// profiles with the plain default, so every field has a value per profile.
+func getProfiles(fields []StructField) []string {
+	profiles := []string{}
+	for _, field := range fields {
+		for profile := range field.ProfileDefaults {
+			if !inSlice(profiles, profile) {
+				profiles = append(profiles, profile)
+			}
+		}
+	}
+
+	sort.Strings(profiles)
+
+	for i := range fields {
+		if len(profiles) == 0 {
+			break
+		}
+
+		if fields[i].ProfileDefaults == nil {
+			fields[i].ProfileDefaults = map[string]string{}
+		}
+
+		for _, profile := range profiles {
+			if _, ok := fields[i].ProfileDefaults[profile]; !ok {
+				fields[i].ProfileDefaults[profile] = fields[i].DefaultValue
+			}
+		}
+	}
+
+	return profiles
+}
+
 func (generator *Generator) Visit(node ast.Node) ast.Visitor {
 	switch node := node.(type) {
 	case *ast.Package:
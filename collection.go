@@ -0,0 +1,235 @@
+package ko
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// validateCollection checks the slice, array or map held by the field
+// against minitems, maxitems, unique and keypattern tags.
+func validateCollection(
+	value reflect.Value,
+	structField reflect.StructField,
+	prefix []string,
+	promote bool,
+) error {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			value = reflect.Zero(value.Type().Elem())
+			break
+		}
+
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+	default:
+		return nil
+	}
+
+	field := strings.Join(prefix, ".")
+
+	for _, tag := range []string{"minitems", "maxitems"} {
+		limit, ok := structField.Tag.Lookup(tag)
+		if !ok {
+			continue
+		}
+
+		number, err := strconv.Atoi(limit)
+		if err != nil || number < 0 {
+			return newFieldError(prefix, fmt.Errorf(
+				"field %q: invalid %s %q",
+				field,
+				tag,
+				limit,
+			))
+		}
+
+		switch {
+		case tag == "minitems" && value.Len() < number:
+			return newFieldError(prefix, fmt.Errorf(
+				"field %q must have at least %s, got %d",
+				field,
+				pluralItems(number),
+				value.Len(),
+			))
+
+		case tag == "maxitems" && value.Len() > number:
+			return newFieldError(prefix, fmt.Errorf(
+				"field %q must have at most %s, got %d",
+				field,
+				pluralItems(number),
+				value.Len(),
+			))
+		}
+	}
+
+	if key := structField.Tag.Get("unique"); key != "" && key != "false" {
+		err := checkUnique(value, key, prefix, promote)
+		if err != nil {
+			return err
+		}
+	}
+
+	if pattern := structField.Tag.Get("keypattern"); pattern != "" {
+		err := checkKeyPattern(value, pattern, prefix)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkUnique reports the first item which repeats the value of an earlier
+// item. With unique:"true" items are compared as a whole, otherwise by the
+// field with the given key. Zero values are not compared.
+func checkUnique(
+	items reflect.Value,
+	key string,
+	prefix []string,
+	promote bool,
+) error {
+	field := strings.Join(prefix, ".")
+
+	if items.Kind() == reflect.Map {
+		return newFieldError(prefix, fmt.Errorf(
+			"field %q: unique is not supported for maps, keys are unique",
+			field,
+		))
+	}
+
+	seen := map[string]int{}
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		path := pushItem(prefix, strconv.Itoa(i))
+
+		if key != "true" {
+			var err error
+			item, err = uniqueKey(item, key, promote)
+			if err != nil {
+				return newFieldError(path, fmt.Errorf(
+					"field %q: %s",
+					strings.Join(path, "."),
+					err,
+				))
+			}
+
+			path = push(path, key)
+		}
+
+		if !item.IsValid() || item.IsZero() {
+			continue
+		}
+
+		text := fmt.Sprintf("%v", item.Interface())
+		if index, ok := seen[text]; ok {
+			return newFieldError(path, fmt.Errorf(
+				"field %q must be unique, %q is already used by %q",
+				strings.Join(path, "."),
+				text,
+				strings.Join(pushItem(prefix, strconv.Itoa(index)), "."),
+			))
+		}
+
+		seen[text] = i
+	}
+
+	return nil
+}
+
+// uniqueKey returns the field with the key of the struct held by item. An
+// invalid value is returned if the item or a pointer on the way is nil.
+func uniqueKey(
+	item reflect.Value,
+	key string,
+	promote bool,
+) (reflect.Value, error) {
+	for item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface {
+		if item.IsNil() {
+			return reflect.Value{}, nil
+		}
+
+		item = item.Elem()
+	}
+
+	if item.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf(
+			"unable to find key %q in %s, struct expected",
+			key,
+			item.Type(),
+		)
+	}
+
+	field, ok := findField(item.Type(), key, promote)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf(
+			"unknown key %q in %s",
+			key,
+			item.Type(),
+		)
+	}
+
+	value, err := item.FieldByIndexErr(field.Index)
+	if err != nil {
+		return reflect.Value{}, nil
+	}
+
+	return value, nil
+}
+
+// checkKeyPattern reports the first map key, in sorted order, which does not
+// match the pattern.
+func checkKeyPattern(items reflect.Value, pattern string, prefix []string) error {
+	field := strings.Join(prefix, ".")
+
+	if items.Kind() != reflect.Map {
+		return newFieldError(prefix, fmt.Errorf(
+			"field %q: keypattern is supported only for maps",
+			field,
+		))
+	}
+
+	expression, err := regexp.Compile(pattern)
+	if err != nil {
+		return newFieldError(prefix, fmt.Errorf(
+			"field %q: invalid keypattern %q: %s",
+			field,
+			pattern,
+			err,
+		))
+	}
+
+	keys := []string{}
+	for _, key := range items.MapKeys() {
+		keys = append(keys, fmt.Sprint(key.Interface()))
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if !expression.MatchString(key) {
+			return newFieldError(pushItem(prefix, key), fmt.Errorf(
+				"field %q: key %q does not match %s",
+				field,
+				key,
+				pattern,
+			))
+		}
+	}
+
+	return nil
+}
+
+func pluralItems(number int) string {
+	if number == 1 {
+		return "1 item"
+	}
+
+	return strconv.Itoa(number) + " items"
+}
@@ -0,0 +1,146 @@
+package ko
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type collectionBackend struct {
+	Name string `yaml:"name"`
+	Addr string `yaml:"addr" default:"localhost:80"`
+}
+
+type collectionConfig struct {
+	Routes   []string                     `yaml:"routes" minitems:"1"`
+	Shards   []int                        `yaml:"shards" maxitems:"2" unique:"true"`
+	Backends []*collectionBackend         `yaml:"backends" unique:"name"`
+	Addrs    []collectionBackend          `yaml:"addrs" unique:"addr"`
+	Pools    map[string]collectionBackend `yaml:"pools" keypattern:"^[a-z]+$" maxitems:"3"`
+}
+
+func TestCollection(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+routes: [/]
+shards: [1, 2]
+backends: [{name: a}, {name: b}, {}, {}]
+addrs: [{name: a, addr: "a:80"}, {name: b}]
+pools: {main: {}, spare: {}}
+`)
+	defer os.Remove(path)
+
+	var cfg collectionConfig
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+	test.Len(cfg.Backends, 4)
+}
+
+func TestCollection_MinItems(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`shards: [1]`)
+	defer os.Remove(path)
+
+	var cfg collectionConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "routes" must have at least 1 item, got 0`,
+	)
+}
+
+func TestCollection_MaxItems(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`{routes: [/], shards: [1, 2, 3]}`)
+	defer os.Remove(path)
+
+	var cfg collectionConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "shards" must have at most 2 items, got 3`,
+	)
+}
+
+func TestCollection_Unique(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`{routes: [/], shards: [1, 1]}`)
+	defer os.Remove(path)
+
+	var cfg collectionConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "shards[1]" must be unique, "1" is already used by "shards[0]"`,
+	)
+
+	path = write(`{routes: [/], backends: [{name: a}, {name: b}, {name: a}]}`)
+	defer os.Remove(path)
+
+	test.EqualError(
+		Load(path, &collectionConfig{}, yaml.Unmarshal),
+		`field "backends[2].name" must be unique, `+
+			`"a" is already used by "backends[0]"`,
+	)
+}
+
+func TestCollection_UniqueDefaults(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`{routes: [/], addrs: [{name: a}, {name: b}]}`)
+	defer os.Remove(path)
+
+	var cfg collectionConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "addrs[1].addr" must be unique, `+
+			`"localhost:80" is already used by "addrs[0]"`,
+	)
+}
+
+func TestCollection_KeyPattern(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`{routes: [/], pools: {main: {}, Spare: {}}}`)
+	defer os.Remove(path)
+
+	var cfg collectionConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "pools": key "Spare" does not match ^[a-z]+$`,
+	)
+}
+
+func TestCollection_InvalidTags(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`items: [a]`)
+	defer os.Remove(path)
+
+	var invalidLimit struct {
+		Items []string `yaml:"items" minitems:"one"`
+	}
+	err := Load(path, &invalidLimit, yaml.Unmarshal)
+	test.EqualError(err, `field "items": invalid minitems "one"`)
+
+	path = write(`items: [{name: a}]`)
+	defer os.Remove(path)
+
+	var unknownKey struct {
+		Items []collectionBackend `yaml:"items" unique:"id"`
+	}
+	err = Load(path, &unknownKey, yaml.Unmarshal)
+	test.EqualError(
+		err,
+		`field "items[0]": unknown key "id" in ko.collectionBackend`,
+	)
+
+	var keyPattern struct {
+		Items []collectionBackend `yaml:"items" keypattern:"^a$"`
+	}
+	err = Load(path, &keyPattern, yaml.Unmarshal)
+	test.EqualError(err, `field "items": keypattern is supported only for maps`)
+}
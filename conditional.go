@@ -0,0 +1,214 @@
+package ko
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// validateGroups checks that exactly one field of every oneof_group of the
+// struct is specified.
+func validateGroups(resource reflect.Value, prefix []string) error {
+	var (
+		groups    = []string{}
+		members   = map[string][]string{}
+		specified = map[string][]string{}
+	)
+
+	resourceStruct := resource.Type()
+	for index := 0; index < resourceStruct.NumField(); index++ {
+		structField := resourceStruct.Field(index)
+
+		group := structField.Tag.Get("oneof_group")
+		if group == "" || structField.PkgPath != "" {
+			continue
+		}
+
+		if !inSlice(groups, group) {
+			groups = append(groups, group)
+		}
+
+		path := strings.Join(push(prefix, getFieldKey(structField)), ".")
+
+		members[group] = append(members[group], path)
+		if !resource.Field(index).IsZero() {
+			specified[group] = append(specified[group], path)
+		}
+	}
+
+	for _, group := range groups {
+		switch len(specified[group]) {
+		case 1:
+			continue
+
+		case 0:
+			return newFieldError(prefix, fmt.Errorf(
+				"one of fields %s of group %q is required, "+
+					"but none is specified",
+				quoteJoin(members[group]),
+				group,
+			))
+
+		default:
+			return newFieldError(prefix, fmt.Errorf(
+				"only one of fields %s of group %q can be specified, "+
+					"but %s are specified",
+				quoteJoin(members[group]),
+				group,
+				quoteJoin(specified[group]),
+			))
+		}
+	}
+
+	return nil
+}
+
+func quoteJoin(items []string) string {
+	quoted := []string{}
+	for _, item := range items {
+		quoted = append(quoted, fmt.Sprintf("%q", item))
+	}
+
+	return strings.Join(quoted, ", ")
+}
+
+// validateConditions checks required_if, required_with, required_without
+// and excluded_with tags of struct fields against their siblings. The tags
+// are checked whenever the struct itself is validated, regardless of
+// required tags of its parents, because the tags describe the conditions
+// themselves.
+func validateConditions(
+	resource reflect.Value,
+	prefix []string,
+	promote bool,
+) error {
+	resourceStruct := resource.Type()
+	for index := 0; index < resourceStruct.NumField(); index++ {
+		var (
+			resourceField   = resource.Field(index)
+			structField     = resourceStruct.Field(index)
+			path            = push(prefix, getFieldKey(structField))
+			requiredIf      = structField.Tag.Get("required_if")
+			requiredWith    = structField.Tag.Get("required_with")
+			requiredWithout = structField.Tag.Get("required_without")
+			excludedWith    = structField.Tag.Get("excluded_with")
+		)
+
+		if structField.PkgPath != "" {
+			continue
+		}
+
+		specified := !resourceField.IsZero()
+
+		if requiredIf != "" && !specified {
+			conditions := []string{}
+			matched := true
+			for _, condition := range strings.Split(requiredIf, ",") {
+				key, expected, _ := strings.Cut(condition, "=")
+
+				sibling, err := getSibling(resource, path, "required_if", key, promote)
+				if err != nil {
+					return err
+				}
+
+				sibling = reflect.Indirect(sibling)
+				if !sibling.IsValid() ||
+					fmt.Sprint(sibling.Interface()) != expected {
+					matched = false
+					break
+				}
+
+				conditions = append(
+					conditions,
+					fmt.Sprintf("%q is %s", siblingPath(prefix, key), expected),
+				)
+			}
+
+			if matched {
+				return newFieldError(path, fmt.Errorf(
+					"field %q is required when %s",
+					strings.Join(path, "."),
+					strings.Join(conditions, " and "),
+				))
+			}
+		}
+
+		if requiredWith != "" && !specified {
+			for _, key := range strings.Split(requiredWith, ",") {
+				sibling, err := getSibling(resource, path, "required_with", key, promote)
+				if err != nil {
+					return err
+				}
+
+				if !sibling.IsZero() {
+					return newFieldError(path, fmt.Errorf(
+						"field %q is required when %q is specified",
+						strings.Join(path, "."),
+						siblingPath(prefix, key),
+					))
+				}
+			}
+		}
+
+		if requiredWithout != "" && !specified {
+			for _, key := range strings.Split(requiredWithout, ",") {
+				sibling, err := getSibling(resource, path, "required_without", key, promote)
+				if err != nil {
+					return err
+				}
+
+				if sibling.IsZero() {
+					return newFieldError(path, fmt.Errorf(
+						"field %q is required when %q is not specified",
+						strings.Join(path, "."),
+						siblingPath(prefix, key),
+					))
+				}
+			}
+		}
+
+		if excludedWith != "" && specified {
+			for _, key := range strings.Split(excludedWith, ",") {
+				sibling, err := getSibling(resource, path, "excluded_with", key, promote)
+				if err != nil {
+					return err
+				}
+
+				if !sibling.IsZero() {
+					return newFieldError(path, fmt.Errorf(
+						"field %q must not be specified when %q is specified",
+						strings.Join(path, "."),
+						siblingPath(prefix, key),
+					))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// getSibling returns value of the field of resource with the given key.
+func getSibling(
+	resource reflect.Value,
+	path []string,
+	tag string,
+	key string,
+	promote bool,
+) (reflect.Value, error) {
+	field, ok := findField(resource.Type(), strings.TrimSpace(key), promote)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf(
+			"field %q: %s refers to unknown field %q",
+			strings.Join(path, "."),
+			tag,
+			key,
+		)
+	}
+
+	return fieldByIndex(resource, field), nil
+}
+
+func siblingPath(prefix []string, key string) string {
+	return strings.Join(push(prefix, strings.TrimSpace(key)), ".")
+}
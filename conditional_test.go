@@ -0,0 +1,205 @@
+package ko
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type conditionalConfig struct {
+	TLS struct {
+		Enabled bool   `yaml:"enabled"`
+		Cert    string `yaml:"cert" required_if:"enabled=true"`
+		Key     string `yaml:"key" required_with:"cert"`
+	} `yaml:"tls"`
+
+	Auth struct {
+		Token    string `yaml:"token" required_without:"password" excluded_with:"password"`
+		Password string `yaml:"password"`
+	} `yaml:"auth" required:"true"`
+}
+
+func TestConditional(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+tls: {enabled: true, cert: a.pem, key: a.key}
+auth: {token: x}
+`)
+	defer os.Remove(path)
+
+	var cfg conditionalConfig
+	test.NoError(Load(path, &cfg, yaml.Unmarshal))
+}
+
+func TestConditional_RequiredIf(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+tls: {enabled: true}
+auth: {password: x}
+`)
+	defer os.Remove(path)
+
+	var cfg conditionalConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "tls.cert" is required when "tls.enabled" is true`,
+	)
+
+	path = write(`
+tls: {enabled: false, key: a.key}
+auth: {password: x}
+`)
+	defer os.Remove(path)
+
+	test.NoError(Load(path, &conditionalConfig{}, yaml.Unmarshal))
+}
+
+func TestConditional_RequiredWith(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+tls: {cert: a.pem}
+auth: {password: x}
+`)
+	defer os.Remove(path)
+
+	var cfg conditionalConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "tls.key" is required when "tls.cert" is specified`,
+	)
+}
+
+func TestConditional_RequiredWithout(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(``)
+	defer os.Remove(path)
+
+	var cfg conditionalConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "auth.token" is required when "auth.password" is not specified`,
+	)
+}
+
+func TestConditional_ExcludedWith(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`auth: {token: x, password: y}`)
+	defer os.Remove(path)
+
+	var cfg conditionalConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "auth.token" must not be specified when "auth.password" is specified`,
+	)
+}
+
+func TestConditional_UnknownField(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(``)
+	defer os.Remove(path)
+
+	type config struct {
+		Cert string `yaml:"cert" required_with:"kye"`
+	}
+
+	var cfg config
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "cert": required_with refers to unknown field "kye"`,
+	)
+}
+
+type oneofConfig struct {
+	Storage struct {
+		S3 *struct {
+			Bucket string `yaml:"bucket" required:"true"`
+		} `yaml:"s3" oneof_group:"backend"`
+		GCS struct {
+			Bucket string `yaml:"bucket" required:"true"`
+		} `yaml:"gcs" oneof_group:"backend"`
+		Local struct {
+			Dir string `yaml:"dir" required:"true" default:"/var/lib/app"`
+		} `yaml:"local" oneof_group:"backend"`
+	} `yaml:"storage" required:"true"`
+}
+
+func TestOneofGroup(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`storage: {s3: {bucket: data}}`)
+	defer os.Remove(path)
+
+	var cfg oneofConfig
+	test.NoError(Load(path, &cfg, yaml.Unmarshal))
+	test.Equal("data", cfg.Storage.S3.Bucket)
+	test.Equal("", cfg.Storage.Local.Dir)
+}
+
+func TestOneofGroup_None(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`storage: {}`)
+	defer os.Remove(path)
+
+	var cfg oneofConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`one of fields "storage.s3", "storage.gcs", "storage.local" `+
+			`of group "backend" is required, but none is specified`,
+	)
+}
+
+func TestOneofGroup_Several(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`storage: {s3: {bucket: data}, gcs: {bucket: data}}`)
+	defer os.Remove(path)
+
+	var cfg oneofConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`only one of fields "storage.s3", "storage.gcs", "storage.local" `+
+			`of group "backend" can be specified, `+
+			`but "storage.s3", "storage.gcs" are specified`,
+	)
+}
+
+func TestOneofGroup_ChosenIsRequired(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`storage: {s3: {}}`)
+	defer os.Remove(path)
+
+	var cfg oneofConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "storage.s3.bucket" is required, but no value specified`,
+	)
+}
+
+func TestOneofGroup_Env(t *testing.T) {
+	test := assert.New(t)
+
+	os.Setenv("KO_TEST_TOKEN", "secret")
+	defer os.Unsetenv("KO_TEST_TOKEN")
+
+	path := write(``)
+	defer os.Remove(path)
+
+	type config struct {
+		Token    string `yaml:"token" env:"KO_TEST_TOKEN" oneof_group:"auth"`
+		Password string `yaml:"password" oneof_group:"auth"`
+	}
+
+	var cfg config
+	test.NoError(Load(path, &cfg, yaml.Unmarshal))
+	test.Equal("secret", cfg.Token)
+}
@@ -0,0 +1,143 @@
+package ko
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Decoders is an option for Load method which maps types to functions
+	// decoding env and default values of fields of these types, including
+	// default_<profile> values. Decoders are looked up by the field type
+	// and, for pointer fields, by the type it points to. Several Decoders
+	// options are merged, the later ones win.
+	//
+	// Fields without a decoder are decoded with UnmarshalText if they
+	// implement encoding.TextUnmarshaler, and with yaml.Unmarshal
+	// otherwise.
+	Decoders map[reflect.Type]func(value string) (interface{}, error)
+)
+
+// DecoderFor returns Decoders with the single decoder for values of type T,
+// e.g. ko.DecoderFor(regexp.Compile).
+func DecoderFor[T any](decode func(value string) (T, error)) Decoders {
+	return Decoders{
+		reflect.TypeOf((*T)(nil)).Elem(): func(value string) (interface{}, error) {
+			return decode(value)
+		},
+	}
+}
+
+// defaultDecoders are used for types which have neither a decoder passed to
+// Load nor UnmarshalText.
+var defaultDecoders = Decoders{
+	reflect.TypeOf(url.URL{}): func(value string) (interface{}, error) {
+		parsed, err := url.Parse(value)
+		if err != nil {
+			return nil, err
+		}
+
+		return *parsed, nil
+	},
+}
+
+var textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// decodeValue decodes env or default value into the addressable target.
+func (loader *loader) decodeValue(target reflect.Value, value string) error {
+	if decode, ok := loader.getDecoder(target.Type()); ok {
+		return setDecoded(target, decode, value)
+	}
+
+	if target.Kind() == reflect.Ptr {
+		if decode, ok := loader.getDecoder(target.Type().Elem()); ok {
+			allocated := reflect.New(target.Type().Elem())
+
+			err := setDecoded(allocated.Elem(), decode, value)
+			if err != nil {
+				return err
+			}
+
+			target.Set(allocated)
+
+			return nil
+		}
+
+		if target.Type().Implements(textUnmarshaler) {
+			allocated := reflect.New(target.Type().Elem())
+
+			err := allocated.Interface().(encoding.TextUnmarshaler).
+				UnmarshalText([]byte(value))
+			if err != nil {
+				return err
+			}
+
+			target.Set(allocated)
+
+			return nil
+		}
+	}
+
+	if unmarshaler, ok := target.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText([]byte(value))
+	}
+
+	return yaml.Unmarshal([]byte(value), target.Addr().Interface())
+}
+
+func (loader *loader) getDecoder(
+	target reflect.Type,
+) (func(string) (interface{}, error), bool) {
+	if decode, ok := loader.decoders[target]; ok {
+		return decode, true
+	}
+
+	if target.Implements(textUnmarshaler) ||
+		reflect.PtrTo(target).Implements(textUnmarshaler) {
+		return nil, false
+	}
+
+	decode, ok := defaultDecoders[target]
+
+	return decode, ok
+}
+
+// isDecodable reports whether values of the type are decoded as a whole
+// rather than field by field.
+func (loader *loader) isDecodable(target reflect.Type) bool {
+	if hasCustomUnmarshaller(target) {
+		return true
+	}
+
+	_, ok := loader.getDecoder(target)
+
+	return ok
+}
+
+func setDecoded(
+	target reflect.Value,
+	decode func(string) (interface{}, error),
+	value string,
+) error {
+	decoded, err := decode(value)
+	if err != nil {
+		return err
+	}
+
+	result := reflect.ValueOf(decoded)
+	if !result.IsValid() || !result.Type().AssignableTo(target.Type()) {
+		return fmt.Errorf(
+			"decoder returned %T, which is not assignable to %s",
+			decoded,
+			target.Type(),
+		)
+	}
+
+	target.Set(result)
+
+	return nil
+}
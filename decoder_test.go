@@ -0,0 +1,129 @@
+package ko
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type decoderHost struct {
+	Name string
+	Port string
+}
+
+func parseDecoderHost(value string) (decoderHost, error) {
+	name, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return decoderHost{}, err
+	}
+
+	return decoderHost{Name: name, Port: port}, nil
+}
+
+func TestDecoders(t *testing.T) {
+	test := assert.New(t)
+
+	os.Setenv("KO_TEST_DECODER_IP", "10.0.0.1")
+	defer os.Unsetenv("KO_TEST_DECODER_IP")
+
+	os.Setenv("KO_TEST_DECODER_LEVEL", "warn")
+	defer os.Unsetenv("KO_TEST_DECODER_LEVEL")
+
+	path := write(``)
+	defer os.Remove(path)
+
+	var cfg struct {
+		IP       net.IP         `yaml:"ip" env:"KO_TEST_DECODER_IP"`
+		Level    slog.Level     `yaml:"level" env:"KO_TEST_DECODER_LEVEL"`
+		Pattern  *regexp.Regexp `yaml:"pattern" default:"^[a-z]+$"`
+		Endpoint *url.URL       `yaml:"endpoint" default:"https://example.com/api"`
+		Upstream decoderHost    `yaml:"upstream" default:"localhost:8080"`
+		Backup   *decoderHost   `yaml:"backup" default:"backup:9090" default_prod:"prod:9090"`
+		Tags     []string       `yaml:"tags" default:"[a, b]"`
+		Limits   map[string]int `yaml:"limits" default:"{cpu: 2}"`
+	}
+
+	err := Load(path, &cfg, yaml.Unmarshal, DecoderFor(parseDecoderHost))
+	test.NoError(err)
+
+	test.Equal(net.ParseIP("10.0.0.1"), cfg.IP)
+	test.Equal(slog.LevelWarn, cfg.Level)
+	test.Equal("^[a-z]+$", cfg.Pattern.String())
+	test.Equal("example.com", cfg.Endpoint.Host)
+	test.Equal(decoderHost{Name: "localhost", Port: "8080"}, cfg.Upstream)
+	test.Equal(&decoderHost{Name: "backup", Port: "9090"}, cfg.Backup)
+	test.Equal([]string{"a", "b"}, cfg.Tags)
+	test.Equal(map[string]int{"cpu": 2}, cfg.Limits)
+}
+
+func TestDecoders_Override(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`profiles: {prod: {}}`)
+	defer os.Remove(path)
+
+	var cfg struct {
+		Backup *decoderHost `yaml:"backup" default:"backup:9090" default_prod:"prod:9090"`
+		Name   string       `yaml:"name" default:"main"`
+	}
+
+	err := Load(
+		path,
+		&cfg,
+		yaml.Unmarshal,
+		Profile("prod"),
+		DecoderFor(parseDecoderHost),
+		Decoders{
+			reflect.TypeOf(""): func(value string) (interface{}, error) {
+				return strings.ToUpper(value), nil
+			},
+		},
+	)
+	test.NoError(err)
+	test.Equal(&decoderHost{Name: "prod", Port: "9090"}, cfg.Backup)
+	test.Equal("MAIN", cfg.Name)
+}
+
+func TestDecoders_Error(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(``)
+	defer os.Remove(path)
+
+	var cfg struct {
+		Upstream decoderHost `yaml:"upstream" default:"localhost"`
+		Level    slog.Level  `yaml:"level"`
+	}
+
+	err := Load(path, &cfg, yaml.Unmarshal, DecoderFor(parseDecoderHost))
+	test.EqualError(
+		err,
+		`unable to unmarshal default value for field "upstream"`+"\n"+
+			`└─ address localhost: missing port in address`,
+	)
+
+	err = Load(
+		path,
+		&cfg,
+		yaml.Unmarshal,
+		Decoders{
+			reflect.TypeOf(decoderHost{}): func(value string) (interface{}, error) {
+				return fmt.Sprint(value), nil
+			},
+		},
+	)
+	test.EqualError(
+		err,
+		`unable to unmarshal default value for field "upstream"`+"\n"+
+			`└─ decoder returned string, which is not assignable to ko.decoderHost`,
+	)
+}
@@ -0,0 +1,390 @@
+package ko
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/reconquest/karma-go"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// WarningHook is an option for Load method which receives warnings about
+	// problems that do not fail loading, like usage of deprecated keys.
+	WarningHook func(message string)
+)
+
+// migrate copies values of deprecated keys to fields which list them in
+// aliases tags and warns about usage of deprecated keys.
+func (loader *loader) migrate(resource interface{}) error {
+	resourceType := reflect.TypeOf(resource)
+	if len(collectTag(resourceType, "aliases")) == 0 &&
+		len(collectTag(resourceType, "deprecated")) == 0 {
+		return nil
+	}
+
+	document, err := loader.document()
+	if err != nil {
+		return err
+	}
+
+	return loader.applyAliases(
+		reflect.ValueOf(resource), document, document, nil, nil,
+	)
+}
+
+// document decodes all loaded files into generic data and merges them in
+// the order they were applied, with the selected profile on top.
+func (loader *loader) document() (interface{}, error) {
+	var result interface{}
+	profiles := []interface{}{}
+
+	for _, file := range loader.files {
+		var document interface{}
+		err := loader.unmarshaller(file.data, &document)
+		if err != nil {
+			return nil, err
+		}
+
+		if mapping, ok := document.(map[string]interface{}); ok {
+			if profile, ok := mapping["profiles"].(map[string]interface{}); ok {
+				profiles = append(profiles, profile[loader.profile])
+			}
+		}
+
+		result = mergeDocuments(result, document)
+	}
+
+	if loader.profile != "" {
+		for _, profile := range profiles {
+			result = mergeDocuments(result, profile)
+		}
+	}
+
+	return result, nil
+}
+
+// mergeDocuments merges generic data of overlay into base the same way
+// unmarshallers do when decoding into an already filled struct: mappings are
+// merged recursively, everything else is replaced.
+func mergeDocuments(base, overlay interface{}) interface{} {
+	baseMapping, ok := base.(map[string]interface{})
+	if !ok {
+		if overlay == nil {
+			return base
+		}
+
+		return overlay
+	}
+
+	overlayMapping, ok := overlay.(map[string]interface{})
+	if !ok {
+		if overlay == nil {
+			return base
+		}
+
+		return overlay
+	}
+
+	result := map[string]interface{}{}
+	for key, value := range baseMapping {
+		result[key] = value
+	}
+
+	for key, value := range overlayMapping {
+		result[key] = mergeDocuments(result[key], value)
+	}
+
+	return result
+}
+
+// applyAliases walks resource along with generic data of the loaded
+// document. Fields which are absent in the document are filled from keys
+// listed in their aliases tag, and keys of fields with deprecated tag
+// produce warnings. Aliases are key paths relative to the document root or,
+// for fields inside slices and maps, to the item.
+func (loader *loader) applyAliases(
+	resource reflect.Value,
+	data interface{},
+	item interface{},
+	itemPrefix []string,
+	prefix []string,
+) error {
+	for resource.Kind() == reflect.Ptr || resource.Kind() == reflect.Interface {
+		if resource.IsNil() {
+			if resource.Kind() != reflect.Ptr || !resource.CanSet() {
+				return nil
+			}
+
+			// Aliased keys may point into a section which is not present
+			// in the document at all, so the section has to be allocated
+			// to find out. Types which are already being allocated up the
+			// stack are skipped, otherwise self-referencing types would
+			// be allocated forever.
+			target := resource.Type().Elem()
+			if len(collectAliases(target, loader.promotesEmbedded())) == 0 ||
+				inSlice(loader.allocating, target) {
+				return nil
+			}
+
+			loader.allocating = push(loader.allocating, target)
+			allocated := reflect.New(target)
+
+			err := loader.applyAliases(
+				allocated.Elem(), data, item, itemPrefix, prefix,
+			)
+
+			loader.allocating = loader.allocating[:len(loader.allocating)-1]
+			if err != nil {
+				return err
+			}
+
+			if !allocated.Elem().IsZero() {
+				resource.Set(allocated)
+			}
+
+			return nil
+		}
+
+		if !loader.visit("aliases", resource) {
+			return nil
+		}
+
+		resource = resource.Elem()
+	}
+
+	if hasCustomUnmarshaller(resource.Type()) {
+		return nil
+	}
+
+	switch resource.Kind() {
+	case reflect.Struct:
+		mapping, _ := data.(map[string]interface{})
+
+		resourceStruct := resource.Type()
+		for index := 0; index < resourceStruct.NumField(); index++ {
+			var (
+				resourceField = resource.Field(index)
+				structField   = resourceStruct.Field(index)
+				fieldPrefix   = push(prefix, getFieldKey(structField))
+			)
+
+			if isInline(structField, loader.promotesEmbedded()) {
+				err := loader.applyAliases(
+					resourceField, data, item, itemPrefix, prefix,
+				)
+				if err != nil {
+					return err
+				}
+
+				continue
+			}
+
+			if structField.PkgPath != "" {
+				continue
+			}
+
+			fieldData, ok := lookupField(
+				mapping,
+				resourceStruct,
+				structField,
+				loader.promotesEmbedded(),
+			)
+
+			deprecated := structField.Tag.Get("deprecated")
+			if ok && deprecated != "" {
+				loader.emit(Event{
+					Kind:    EventDeprecated,
+					Path:    strings.Join(fieldPrefix, "."),
+					Message: deprecated,
+				})
+			}
+
+			aliases := structField.Tag.Get("aliases")
+			if !ok && aliases != "" {
+				for _, alias := range strings.Split(aliases, ",") {
+					aliasData, ok := lookupPath(item, alias)
+					if !ok {
+						continue
+					}
+
+					// Keys of data are spelled the way the unmarshaller
+					// names fields, decodeGeneric expects yaml names.
+					err := decodeGeneric(
+						normalizeKeys(
+							resourceField.Type(),
+							aliasData,
+							loader.promotesEmbedded(),
+						),
+						resourceField,
+					)
+					if err != nil {
+						return karma.Format(
+							err,
+							"unable to unmarshal value of key %q to field %q",
+							strings.Join(push(itemPrefix, alias), "."),
+							strings.Join(fieldPrefix, "."),
+						)
+					}
+
+					loader.emit(Event{
+						Kind: EventDeprecated,
+						Path: strings.Join(push(itemPrefix, alias), "."),
+						Message: fmt.Sprintf(
+							"use %q instead",
+							strings.Join(fieldPrefix, "."),
+						),
+					})
+
+					fieldData = aliasData
+					break
+				}
+			}
+
+			err := loader.applyAliases(
+				resourceField, fieldData, item, itemPrefix, fieldPrefix,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		mapping, _ := data.(map[string]interface{})
+
+		for _, key := range resource.MapKeys() {
+			itemData := mapping[fmt.Sprint(key.Interface())]
+			itemPrefix := pushItem(prefix, fmt.Sprint(key.Interface()))
+
+			value := reflect.New(resource.Type().Elem()).Elem()
+			value.Set(resource.MapIndex(key))
+
+			err := loader.applyAliases(
+				value, itemData, itemData, itemPrefix, itemPrefix,
+			)
+			if err != nil {
+				return err
+			}
+
+			resource.SetMapIndex(key, value)
+		}
+
+	case reflect.Slice, reflect.Array:
+		items := reflect.ValueOf(data)
+
+		for i := 0; i < resource.Len(); i++ {
+			var itemData interface{}
+			if items.Kind() == reflect.Slice && i < items.Len() {
+				itemData = items.Index(i).Interface()
+			}
+
+			itemPrefix := pushItem(prefix, fmt.Sprint(i))
+
+			err := loader.applyAliases(
+				resource.Index(i), itemData, itemData, itemPrefix, itemPrefix,
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// lookupField returns generic data of the struct field from mapping decoded
+// from the document.
+func lookupField(
+	mapping map[string]interface{},
+	resourceStruct reflect.Type,
+	structField reflect.StructField,
+	promote bool,
+) (interface{}, bool) {
+	for key, value := range mapping {
+		field, ok := matchField(resourceStruct, key, promote)
+		if ok && field.Name == structField.Name {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// lookupPath returns generic data found by dot-separated key path.
+func lookupPath(data interface{}, path string) (interface{}, bool) {
+	for _, key := range strings.Split(path, ".") {
+		mapping, ok := data.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		data, ok = mapping[key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return data, true
+}
+
+// decodeGeneric stores generic data decoded from a document in target.
+func decodeGeneric(data interface{}, target reflect.Value) error {
+	if !target.CanAddr() {
+		return fmt.Errorf("target field is not addressable")
+	}
+
+	raw, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	return yaml.Unmarshal(raw, target.Addr().Interface())
+}
+
+// collectAliases returns key paths listed in aliases tags of the type and
+// its nested structs. Slices and maps are not entered, because aliases of
+// their items are relative to the items.
+func collectAliases(
+	target reflect.Type,
+	promote bool,
+	seen ...reflect.Type,
+) []string {
+	for target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+
+	if target.Kind() != reflect.Struct || hasCustomUnmarshaller(target) ||
+		inSlice(seen, target) {
+		return nil
+	}
+
+	aliases := []string{}
+	for _, field := range structFields(target, promote) {
+
+		for _, alias := range strings.Split(field.Tag.Get("aliases"), ",") {
+			if alias != "" {
+				aliases = append(aliases, alias)
+			}
+		}
+
+		aliases = append(
+			aliases,
+			collectAliases(field.Type, promote, push(seen, target)...)...,
+		)
+	}
+
+	return aliases
+}
+
+// isAliasKey reports whether key path is one of aliases or leads to one.
+func isAliasKey(aliases []string, path []string) bool {
+	key := strings.Join(path, ".")
+	for _, alias := range aliases {
+		if alias == key || strings.HasPrefix(alias, key+".") {
+			return true
+		}
+	}
+
+	return false
+}
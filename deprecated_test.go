@@ -0,0 +1,130 @@
+package ko
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type deprecatedConfig struct {
+	Database struct {
+		URL  string `yaml:"url" aliases:"db_url,database_url" required:"true"`
+		Pool int    `yaml:"pool" aliases:"database.pool_size" default:"4"`
+	} `yaml:"database" required:"true"`
+
+	Listen string `yaml:"listen" default:":80"`
+	Port   int    `yaml:"port" deprecated:"use listen instead"`
+
+	Routes []struct {
+		Backend string `yaml:"backend" aliases:"upstream"`
+	} `yaml:"routes"`
+}
+
+func TestAliases(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+db_url: postgres://localhost/app
+database:
+  pool_size: 8
+port: 8080
+routes:
+  - upstream: web
+  - backend: api
+`)
+	defer os.Remove(path)
+
+	warnings := []string{}
+
+	var cfg deprecatedConfig
+	test.NoError(Load(
+		path, &cfg, yaml.Unmarshal, Strict(true),
+		WarningHook(func(message string) {
+			warnings = append(warnings, message)
+		}),
+	))
+
+	test.Equal("postgres://localhost/app", cfg.Database.URL)
+	test.Equal(8, cfg.Database.Pool)
+	test.Equal(8080, cfg.Port)
+	test.Equal("web", cfg.Routes[0].Backend)
+	test.Equal("api", cfg.Routes[1].Backend)
+
+	test.Equal([]string{
+		`key "db_url" is deprecated: use "database.url" instead`,
+		`key "database.pool_size" is deprecated: use "database.pool" instead`,
+		`key "port" is deprecated: use listen instead`,
+		`key "routes[0].upstream" is deprecated: use "routes[0].backend" instead`,
+	}, warnings)
+}
+
+func TestAliases_NewKeyWins(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+db_url: postgres://old/app
+database:
+  url: postgres://new/app
+`)
+	defer os.Remove(path)
+
+	warnings := []string{}
+
+	var cfg deprecatedConfig
+	test.NoError(Load(
+		path, &cfg, yaml.Unmarshal,
+		func(message string) {
+			warnings = append(warnings, message)
+		},
+	))
+
+	test.Equal("postgres://new/app", cfg.Database.URL)
+	test.Empty(warnings)
+}
+
+func TestAliases_PointerSection(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+token = "secret"
+`)
+	defer os.Remove(path)
+
+	type config struct {
+		Auth *struct {
+			Token string `toml:"token" aliases:"token"`
+		} `toml:"auth"`
+		Metrics *struct {
+			Listen string `toml:"listen" aliases:"metrics_listen"`
+		} `toml:"metrics"`
+	}
+
+	var cfg config
+	test.NoError(Load(path, &cfg))
+	if test.NotNil(cfg.Auth) {
+		test.Equal("secret", cfg.Auth.Token)
+	}
+	test.Nil(cfg.Metrics)
+}
+
+func TestAliases_TOMLSection(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+[db]
+max_conns = 5
+`)
+	defer os.Remove(path)
+
+	type config struct {
+		Database struct {
+			MaxConns int `toml:"max_conns"`
+		} `toml:"database" aliases:"db"`
+	}
+
+	var cfg config
+	test.NoError(Load(path, &cfg))
+	test.Equal(5, cfg.Database.MaxConns)
+}
@@ -8,7 +8,7 @@
 //
 // # Struct tags
 //
-// Three tags control post-unmarshal behavior:
+// Three core tags fill and check fields after unmarshalling:
 //
 //   - required:"true" — error if the field is still zero after
 //     all fallbacks.
@@ -22,6 +22,109 @@
 // A field with both default and required never triggers the
 // required error.
 //
+// Types implementing encoding.TextUnmarshaler decode env and
+// default values with UnmarshalText; [Decoders] and [DecoderFor]
+// add decoders for other types.
+//
+// Two more tags handle renamed keys:
+//
+//   - aliases:"old_key,section.old" — fill the field from the first
+//     listed key path if its own key is absent.
+//   - deprecated:"message" — warn when the key is used.
+//
+// Warnings are passed to [WarningHook].
+//
+// # Conditional requirements
+//
+// Conditional requirements are expressed with required_if:"key=value",
+// required_with:"key", required_without:"key" and excluded_with:"key",
+// where keys name sibling fields. Sibling fields with the same
+// oneof_group:"name" tag are mutually exclusive: exactly one of
+// them must be set, and it is validated as if it was required.
+//
+// # Network addresses
+//
+// The validate tag checks formats of network addresses: url,
+// hostport, ip, cidr and port; url_scheme:"https,grpc" restricts
+// URL schemes. Other names in the tag are ignored.
+//
+// # File paths
+//
+// String fields with path:"true" have ~ expanded and relative paths
+// resolved against the directory of the file that sets them; options
+// file, dir, exists and writable, e.g. path:"dir,writable", check
+// the resolved path.
+//
+// # Collections
+//
+// Slices, arrays and maps are constrained with minitems:"1",
+// maxitems:"16", unique:"name" to compare items by a key of their
+// structs, and keypattern:"^[a-z]+$" for map keys.
+//
+// Maps must contain keys listed in required_keys:"primary", and
+// get zero entries for missing keys listed in default_keys, which
+// then receive defaults of their fields.
+//
+// A map default with default_merge:"true" is merged into the map
+// from the file, which wins on conflicts, instead of being applied
+// only to an empty map.
+//
+// # Validation rules
+//
+// Invariants spanning fields are expressions like "min <= max" in
+// the rule tag of a blank struct field, with paths relative to the
+// struct, or passed to [Load] as [Rule] with paths from the root.
+//
+// # Polymorphic sections
+//
+// Interface fields are decoded into concrete types chosen by a
+// discriminator key, such as {type: s3, bucket: logs}, when a
+// [Registry] of the types is passed to [Load].
+//
+// # Events
+//
+// [Hook] receives an [Event] for deprecated keys, applied defaults,
+// values taken from environment variables, environment variables
+// ignored because the file sets the field, and, with [EnvPrefix],
+// prefixed environment variables no field uses. [SlogHook] adapts
+// a *slog.Logger.
+//
+// # Includes
+//
+// A file may list other files under the include key. Paths are
+// relative to the including file and may be glob patterns:
+//
+//	include: ["db.yaml", "routes/*.yaml"]
+//
+// Included files are unmarshalled before the including file, so
+// its own values win. Include cycles are reported as errors. The
+// include, extends and profiles keys are directives only if the
+// resource is a struct without its own fields with these keys.
+//
+// # Inheritance
+//
+// A file may declare extends = "base.toml" to load the parent
+// file first and overlay its own values on top. Parents are
+// resolved recursively, relative to the child file, with cycle
+// detection.
+//
+// # Profiles
+//
+// Sections under the profiles key are named profiles. Passing
+// [Profile] or [ProfileEnv] to [Load] overlays the selected
+// section onto the top-level values. Unknown profiles are
+// reported as errors, and errors name the active profile.
+//
+// A default_<profile> tag, e.g. default_prod:"info", replaces the
+// default tag while that profile is active. Such profiles need no
+// section in the files.
+//
+// # Strict mode
+//
+// Pass [Strict](true) to make [Load] fail on keys that do not map
+// to any struct field. All unknown keys are reported with their
+// full paths and the closest valid key.
+//
 // # Required propagation
 //
 // Required validation recurses into nested structs only when
@@ -45,21 +148,52 @@
 //
 //	ko.Load(path, &cfg, yaml.Unmarshal, ko.RequireFile(false))
 //
+// # Durations, sizes and percentages
+//
+// [Duration], [ByteSize] and [Percent] decode values like "7d",
+// "512MiB" and "75%" from every format, env values and default
+// tags, and encode back to the same notation.
+//
 // # Pointer fields
 //
 // Pointer fields distinguish "not set" (nil) from "zero value".
 // A *bool with default:"false" allocates a bool pointing to
 // false; without the default the pointer stays nil.
+// A nil pointer to a struct tagged alloc:"true", or any such
+// pointer with the [Alloc] option, is allocated to apply defaults
+// and env values of its fields and is kept only if any of them
+// ended up set.
 //
 // # Maps
 //
-// Map values must be pointers if you need ko to apply defaults
-// or env fallbacks to their fields. Non-pointer map values are
-// not addressable.
+// Map values may be structs or pointers to structs. Struct values
+// are not addressable, so ko validates a copy of each value and
+// stores it back into the map.
+//
+// Items of slices, arrays and maps are validated at any level,
+// including the top level, and are named like "shards[2].addr"
+// or "[primary].host" in errors.
+// Every pointer is validated once, so shared pointers and cycles
+// are safe; [MaxDepth] limits nesting of structs.
+//
+// # Updating YAML files
+//
+// [UpdateYAML] and [UpdateFile] set values by field path in an
+// existing YAML document, keeping comments and key order, then
+// validate the result with the same rules as [Load].
+//
+// # Error positions
+//
+// Validation errors are [FieldError] values with the field path
+// and, for YAML, JSON and TOML, the [Position] of the key in the
+// file. [FormatError] renders them with a snippet of the file.
 //
 // # Field name resolution
 //
 // Error messages use the yaml, toml, or json struct tag (checked
 // in that order) to name fields. When no tag is present, the Go
-// field name is converted to snake_case.
+// field name is converted to snake_case. Fields of fields with
+// yaml:",inline", and of embedded structs without a key name
+// unless the unmarshaller is yaml, are named and validated as
+// fields of the outer struct.
 package ko
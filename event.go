@@ -0,0 +1,201 @@
+package ko
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type (
+	// Hook is an option for Load method which receives events about
+	// non-fatal things happening while loading configuration.
+	Hook func(Event)
+
+	// EnvPrefix is an option for Load method which makes Load report
+	// environment variables with the given prefix that are not used by any
+	// env tag, which usually means a typo in the variable name.
+	EnvPrefix string
+
+	// EventKind describes what an Event is about.
+	EventKind string
+)
+
+const (
+	// EventDeprecated is emitted when a key marked as deprecated or an
+	// alias of another key is used in configuration.
+	EventDeprecated EventKind = "deprecated"
+
+	// EventDefault is emitted when a field gets its default value.
+	EventDefault EventKind = "default"
+
+	// EventEnv is emitted when a field gets its value from an environment
+	// variable.
+	EventEnv EventKind = "env"
+
+	// EventEnvIgnored is emitted when an environment variable of a field is
+	// set, but the field already has a value from configuration file, which
+	// takes precedence.
+	EventEnvIgnored EventKind = "env_ignored"
+
+	// EventUnknownEnv is emitted for environment variables matching
+	// EnvPrefix that are not used by any field.
+	EventUnknownEnv EventKind = "unknown_env"
+)
+
+// Event is a non-fatal diagnostic produced by Load.
+type Event struct {
+	Kind EventKind
+
+	// Path is the field path or, for deprecated keys, the key path in the
+	// same notation ko uses in errors. Empty for EventUnknownEnv.
+	Path string
+
+	// Env is the name of the environment variable for EventEnv,
+	// EventEnvIgnored and EventUnknownEnv events. Values of environment
+	// variables are never included, because they often hold secrets.
+	Env string
+
+	// Value is the applied value for EventDefault.
+	Value string
+
+	// Message is the text of deprecated tag or the replacement key for
+	// EventDeprecated.
+	Message string
+}
+
+// IsWarning reports whether the event most likely points to a mistake in
+// configuration rather than describes normal operation.
+func (event Event) IsWarning() bool {
+	switch event.Kind {
+	case EventDeprecated, EventEnvIgnored, EventUnknownEnv:
+		return true
+	}
+
+	return false
+}
+
+func (event Event) String() string {
+	switch event.Kind {
+	case EventDeprecated:
+		return fmt.Sprintf("key %q is deprecated: %s", event.Path, event.Message)
+	case EventDefault:
+		return fmt.Sprintf(
+			"field %q is set to default value %q",
+			event.Path,
+			event.Value,
+		)
+	case EventEnv:
+		return fmt.Sprintf(
+			"field %q is set from environment variable %s",
+			event.Path,
+			event.Env,
+		)
+	case EventEnvIgnored:
+		return fmt.Sprintf(
+			"environment variable %s is ignored, "+
+				"field %q is set in configuration file",
+			event.Env,
+			event.Path,
+		)
+	case EventUnknownEnv:
+		return fmt.Sprintf(
+			"environment variable %s is not used by any field",
+			event.Env,
+		)
+	}
+
+	return string(event.Kind)
+}
+
+// SlogHook returns a Hook which logs events to logger: warnings with
+// slog.LevelWarn and everything else with slog.LevelDebug.
+func SlogHook(logger *slog.Logger) Hook {
+	return func(event Event) {
+		level := slog.LevelDebug
+		if event.IsWarning() {
+			level = slog.LevelWarn
+		}
+
+		attrs := []slog.Attr{slog.String("kind", string(event.Kind))}
+		if event.Path != "" {
+			attrs = append(attrs, slog.String("path", event.Path))
+		}
+
+		if event.Env != "" {
+			attrs = append(attrs, slog.String("env", event.Env))
+		}
+
+		logger.LogAttrs(context.Background(), level, event.String(), attrs...)
+	}
+}
+
+func (loader *loader) emit(event Event) {
+	if loader.hook != nil {
+		loader.hook(event)
+	}
+
+	if loader.warningHook != nil && event.IsWarning() {
+		loader.warningHook(event.String())
+	}
+}
+
+// checkUnknownEnv emits EventUnknownEnv for every environment variable with
+// EnvPrefix that is not listed in env tags of resource.
+func (loader *loader) checkUnknownEnv(resource interface{}) {
+	if loader.envPrefix == "" {
+		return
+	}
+
+	known := collectTag(reflect.TypeOf(resource), "env")
+
+	names := []string{}
+	for _, env := range os.Environ() {
+		name := strings.SplitN(env, "=", 2)[0]
+		if strings.HasPrefix(name, loader.envPrefix) && !inSlice(known, name) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		loader.emit(Event{Kind: EventUnknownEnv, Env: name})
+	}
+}
+
+// collectTag returns values of the tag in fields of the type, its nested
+// structs and items of its slices and maps.
+func collectTag(target reflect.Type, tag string, seen ...reflect.Type) []string {
+	for {
+		switch target.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			target = target.Elem()
+			continue
+		}
+
+		break
+	}
+
+	if target.Kind() != reflect.Struct || inSlice(seen, target) {
+		return nil
+	}
+
+	values := []string{}
+	for index := 0; index < target.NumField(); index++ {
+		field := target.Field(index)
+		if value := field.Tag.Get(tag); value != "" {
+			values = append(values, value)
+		}
+
+		values = append(
+			values,
+			collectTag(field.Type, tag, push(seen, target)...)...,
+		)
+	}
+
+	return values
+}
@@ -0,0 +1,113 @@
+package ko
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type eventConfig struct {
+	Listen   string `yaml:"listen" env:"KO_TEST_LISTEN" default:":80"`
+	Database string `yaml:"database" env:"KO_TEST_DATABASE"`
+	Token    string `yaml:"token" env:"KO_TEST_TOKEN"`
+	Port     int    `yaml:"port" deprecated:"use listen"`
+}
+
+func TestHook(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+database: postgres://localhost/app
+port: 80
+`)
+	defer os.Remove(path)
+
+	os.Setenv("KO_TEST_DATABASE", "postgres://env/app")
+	defer os.Unsetenv("KO_TEST_DATABASE")
+
+	os.Setenv("KO_TEST_TOKEN", "secret")
+	defer os.Unsetenv("KO_TEST_TOKEN")
+
+	os.Setenv("KO_TEST_TOKNE", "secret")
+	defer os.Unsetenv("KO_TEST_TOKNE")
+
+	events := []Event{}
+
+	var cfg eventConfig
+	test.NoError(Load(
+		path, &cfg, yaml.Unmarshal, EnvPrefix("KO_TEST_"),
+		Hook(func(event Event) {
+			events = append(events, event)
+		}),
+	))
+
+	test.Equal([]Event{
+		{Kind: EventDeprecated, Path: "port", Message: "use listen"},
+		{Kind: EventDefault, Path: "listen", Value: ":80"},
+		{Kind: EventEnvIgnored, Path: "database", Env: "KO_TEST_DATABASE"},
+		{Kind: EventEnv, Path: "token", Env: "KO_TEST_TOKEN"},
+		{Kind: EventUnknownEnv, Env: "KO_TEST_TOKNE"},
+	}, events)
+
+	test.Equal(
+		`environment variable KO_TEST_DATABASE is ignored, `+
+			`field "database" is set in configuration file`,
+		events[2].String(),
+	)
+}
+
+func TestHook_WarningHook(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`port: 80`)
+	defer os.Remove(path)
+
+	os.Setenv("KO_TEST_LISTEN", ":8080")
+	defer os.Unsetenv("KO_TEST_LISTEN")
+
+	warnings := []string{}
+
+	var cfg eventConfig
+	test.NoError(Load(
+		path, &cfg, yaml.Unmarshal,
+		WarningHook(func(message string) {
+			warnings = append(warnings, message)
+		}),
+	))
+
+	test.Equal([]string{`key "port" is deprecated: use listen`}, warnings)
+	test.Equal(":8080", cfg.Listen)
+}
+
+func TestSlogHook(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`port: 80`)
+	defer os.Remove(path)
+
+	buffer := bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(&buffer, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}))
+
+	var cfg eventConfig
+	test.NoError(Load(path, &cfg, yaml.Unmarshal, SlogHook(logger)))
+
+	test.Equal(
+		`level=WARN msg="key \"port\" is deprecated: use listen" `+
+			`kind=deprecated path=port`+"\n"+
+			`level=DEBUG msg="field \"listen\" is set to default value \":80\"" `+
+			`kind=default path=listen`+"\n",
+		buffer.String(),
+	)
+}
@@ -1,6 +1,6 @@
 module github.com/kovetskiy/ko
 
-go 1.20
+go 1.21
 
 require (
 	github.com/BurntSushi/toml v1.6.0
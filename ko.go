@@ -1,10 +1,14 @@
 package ko
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -30,6 +34,14 @@ type (
 	// RequireFile is an option for Load method which can be used to skip
 	// non-existing file and load all default values for the config fields.
 	RequireFile bool
+
+	// Profile is an option for Load method which selects a section under
+	// the profiles key to be overlaid onto top-level values of the file.
+	Profile string
+
+	// ProfileEnv is an option for Load method which specifies environment
+	// variable to read the profile name from if no Profile is passed.
+	ProfileEnv string
 )
 
 // Load resource data from specified file. unmarshaller variable can be passed
@@ -40,73 +52,497 @@ func Load(
 	resource interface{},
 	opts ...interface{},
 ) error {
-	var unmarshaller Unmarshaller
-	var requireFile bool = true
+	loader := newLoader(opts)
+	loader.path = path
+	loader.resourceType = reflect.TypeOf(resource)
+
+	// Files are unmarshalled into a shadow copy of resource if it has
+	// interface fields decoded by registries, see shadowType.
+	target := resource
+	value := reflect.ValueOf(resource)
+	if value.Kind() == reflect.Ptr && !value.IsNil() &&
+		loader.hasRegistered(value.Type()) {
+		shadow := reflect.New(loader.shadowType(value.Type().Elem()))
+		err := loader.assign(value.Elem(), shadow.Elem(), nil)
+		if err != nil {
+			return err
+		}
+
+		target = shadow.Interface()
+	}
+
+	err := loader.load(path, target)
+	if err == nil {
+		err = loader.applyProfile(target)
+	}
+
+	if err == nil && target != resource {
+		err = loader.assign(
+			reflect.ValueOf(target).Elem(),
+			value.Elem(),
+			nil,
+		)
+	}
+
+	if err == nil && loader.strict {
+		err = loader.checkUnknownKeys(resource)
+	}
+
+	if err == nil {
+		err = loader.migrate(resource)
+	}
+
+	if err == nil {
+		err = loader.validate(resource, true)
+	}
+
+	for _, rule := range loader.rules {
+		if err == nil {
+			err = evaluateRule(
+				rule,
+				reflect.ValueOf(resource),
+				nil,
+				loader.promotesEmbedded(),
+			)
+		}
+	}
+
+	if err == nil {
+		loader.checkUnknownEnv(resource)
+	}
+
+	if err != nil {
+		loader.locate(err, resource)
+	}
+
+	if err != nil && loader.profile != "" {
+		return karma.Describe("profile", loader.profile).Reason(err)
+	}
+
+	return err
+}
+
+type loader struct {
+	unmarshaller Unmarshaller
+	requireFile  bool
+	profile      string
+	strict       bool
+	warningHook  WarningHook
+	hook         Hook
+	envPrefix    string
+	rules        []string
+	registries   []Registry
+	alloc        bool
+	maxDepth     int
+	decoders     Decoders
+
+	// path is the file passed to Load, resourceType is the type of the
+	// resource, keyPositions contains positions of keys found by field
+	// paths, computed on demand.
+	path         string
+	resourceType reflect.Type
+	keyPositions map[string]Position
+
+	// files contains all loaded files in the order they were applied,
+	// profiles contains names of profiles declared in them.
+	files    []loadedFile
+	profiles map[string]struct{}
+
+	// allocating contains types of nil pointers which are allocated while
+	// applying aliases or defaults.
+	allocating []reflect.Type
+
+	// visited contains pointers already processed by passes over resource,
+	// assigned contains copies made for them by assign, depth is the
+	// current depth of nested structs.
+	visited  map[visitKey]reflect.Value
+	assigned map[visitKey]reflect.Value
+	depth    int
+}
+
+type loadedFile struct {
+	path string
+	data []byte
+}
+
+func newLoader(opts []interface{}) *loader {
+	loader := &loader{
+		requireFile: true,
+		profiles:    map[string]struct{}{},
+	}
+
+	var profileEnv string
 
 	for _, opt := range opts {
 		switch opt := opt.(type) {
 		case func([]byte, interface{}) error:
-			unmarshaller = opt
+			loader.unmarshaller = opt
 		case Unmarshaller:
-			unmarshaller = opt
+			loader.unmarshaller = opt
 		case RequireFile:
-			requireFile = bool(opt)
+			loader.requireFile = bool(opt)
+		case Profile:
+			loader.profile = string(opt)
+		case ProfileEnv:
+			profileEnv = string(opt)
+		case Strict:
+			loader.strict = bool(opt)
+		case func(string):
+			loader.warningHook = opt
+		case WarningHook:
+			loader.warningHook = opt
+		case func(Event):
+			loader.hook = opt
+		case Hook:
+			loader.hook = opt
+		case EnvPrefix:
+			loader.envPrefix = string(opt)
+		case Rule:
+			loader.rules = append(loader.rules, string(opt))
+		case Registry:
+			loader.registries = append(loader.registries, opt)
+		case Alloc:
+			loader.alloc = bool(opt)
+		case MaxDepth:
+			loader.maxDepth = int(opt)
+		case Decoders:
+			if loader.decoders == nil {
+				loader.decoders = Decoders{}
+			}
+
+			for target, decode := range opt {
+				loader.decoders[target] = decode
+			}
 		}
 	}
 
-	if unmarshaller == nil {
-		unmarshaller = DefaultUnmarshaller
+	if loader.unmarshaller == nil {
+		loader.unmarshaller = DefaultUnmarshaller
+	}
+
+	if loader.profile == "" && profileEnv != "" {
+		loader.profile = os.Getenv(profileEnv)
 	}
 
+	return loader
+}
+
+// load unmarshals file at path into resource. The file referenced by the
+// extends directive is unmarshalled first, then files listed in the include
+// directive, so the file itself overrides values from its parent and
+// includes. chain contains files that are being loaded and is used to detect
+// cycles.
+func (loader *loader) load(
+	path string,
+	resource interface{},
+	chain ...string,
+) error {
 	data, err := ioutil.ReadFile(path)
 	if err != nil {
-		if os.IsNotExist(err) && requireFile {
+		if len(chain) > 0 || os.IsNotExist(err) && loader.requireFile {
 			return err
 		}
 	}
 
-	err = unmarshaller(data, resource)
-	if err != nil {
-		return err
+	var directives directives
+
+	// Directives can only be specified in documents decoded into a struct.
+	// Keys of a map are data, so a map config may have an extends entry.
+	kind := reflect.Indirect(reflect.ValueOf(resource)).Kind()
+	if len(data) > 0 && kind == reflect.Struct {
+		directives, err = loader.readDirectives(data, reflect.TypeOf(resource))
+		if err != nil {
+			// Syntax errors are reported by unmarshalling the file itself,
+			// only errors specific to directives are reported as such.
+			unmarshalErr := loader.unmarshaller(data, resource)
+			if unmarshalErr != nil {
+				return unmarshalErr
+			}
+
+			return karma.Format(
+				err,
+				"unable to read extends and include directives in %q",
+				path,
+			)
+		}
 	}
 
-	err = validate(resource, true)
-	if err != nil {
-		return err
+	if directives.Extends != "" || len(directives.Include) > 0 {
+		chain, err = pushChain(chain, path)
+		if err != nil {
+			return err
+		}
 	}
 
-	return nil
-}
+	if directives.Extends != "" {
+		parent := directives.Extends
+		if !filepath.IsAbs(parent) {
+			parent = filepath.Join(filepath.Dir(path), parent)
+		}
 
-func validate(
-	value interface{},
-	parentRequired bool,
-	prefix ...string,
-) error {
-	resource := reflect.Indirect(reflect.ValueOf(value))
-	if resource.Kind() == reflect.Map {
-		return nil
+		err := loader.load(parent, resource, chain...)
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to load %q extended by %q",
+				parent,
+				path,
+			)
+		}
 	}
 
-	if resource.Kind() == reflect.Slice {
-		for i := 0; i < resource.Len(); i++ {
-			err := validate(resource.Index(i), parentRequired, prefix...)
+	for _, pattern := range directives.Include {
+		includes, err := resolveInclude(path, pattern)
+		if err != nil {
+			return err
+		}
+
+		for _, include := range includes {
+			err := loader.load(include, resource, chain...)
 			if err != nil {
 				return karma.Format(
 					err,
-					"%d item is invalid",
-					i,
+					"unable to load %q included from %q",
+					include,
+					path,
 				)
 			}
 		}
+	}
+
+	for name := range directives.Profiles {
+		loader.profiles[name] = struct{}{}
+	}
+
+	loader.files = append(loader.files, loadedFile{path: path, data: data})
+
+	return loader.unmarshaller(data, resource)
+}
+
+// directives are keys of config files which are handled by ko itself.
+type directives struct {
+	Extends  string
+	Include  []string
+	Profiles map[string]interface{}
+}
+
+// readDirectives unmarshals directives from the file data. A key is not a
+// directive if the resource has a field with the same key, so configs which
+// use these keys for their own values keep loading as before.
+func (loader *loader) readDirectives(
+	data []byte,
+	resource reflect.Type,
+) (directives, error) {
+	for resource.Kind() == reflect.Ptr {
+		resource = resource.Elem()
+	}
+
+	var result directives
+
+	// Fields of the result are unmarshalled through a struct built of
+	// those of them which are directives for the resource.
+	fields := []reflect.StructField{}
+	for i := 0; i < reflect.TypeOf(result).NumField(); i++ {
+		field := reflect.TypeOf(result).Field(i)
+		key := strings.ToLower(field.Name)
+
+		if _, ok := findField(resource, key, loader.promotesEmbedded()); ok {
+			continue
+		}
+
+		field.Tag = reflect.StructTag(
+			fmt.Sprintf(`yaml:%[1]q toml:%[1]q json:%[1]q`, key),
+		)
+		field.Index = nil
+		field.Offset = 0
+
+		fields = append(fields, field)
+	}
+
+	if len(fields) == 0 {
+		return result, nil
+	}
+
+	wrapper := reflect.New(reflect.StructOf(fields))
 
+	err := loader.unmarshaller(data, wrapper.Interface())
+	if err != nil {
+		return directives{}, err
+	}
+
+	value := reflect.ValueOf(&result).Elem()
+	for _, field := range fields {
+		value.FieldByName(field.Name).Set(
+			wrapper.Elem().FieldByName(field.Name),
+		)
+	}
+
+	return result, nil
+}
+
+// applyProfile overlays the selected profile section of every loaded file
+// onto resource. Profiles are applied after all files are loaded, so a
+// profile value wins over top-level values of any file.
+func (loader *loader) applyProfile(resource interface{}) error {
+	if loader.profile == "" {
 		return nil
 	}
 
+	if _, ok := loader.profiles[loader.profile]; !ok {
+		// Profiles used only by default_<profile> tags are known too, they
+		// have no sections to overlay.
+		known := map[string]struct{}{}
+		collectDefaultProfiles(loader.resourceType, known, map[reflect.Type]bool{})
+		if _, ok := known[loader.profile]; ok {
+			return nil
+		}
+
+		for name := range loader.profiles {
+			known[name] = struct{}{}
+		}
+
+		if len(known) == 0 {
+			return fmt.Errorf(
+				"unknown profile %q, no profiles are declared",
+				loader.profile,
+			)
+		}
+
+		names := []string{}
+		for name := range known {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		return fmt.Errorf(
+			"unknown profile %q, available profiles: %s",
+			loader.profile,
+			strings.Join(names, ", "),
+		)
+	}
+
+	// Unmarshal profiles.<name> section directly into resource by pointing
+	// a field of a wrapper struct to it, so the section is decoded by the
+	// same unmarshaller and with the same rules as the file itself.
+	tags := func(key string) reflect.StructTag {
+		return reflect.StructTag(
+			fmt.Sprintf(`yaml:%[1]q toml:%[1]q json:%[1]q`, key),
+		)
+	}
+
+	section := reflect.StructOf([]reflect.StructField{{
+		Name: "Profile",
+		Type: reflect.TypeOf(resource),
+		Tag:  tags(loader.profile),
+	}})
+
+	wrapper := reflect.New(reflect.StructOf([]reflect.StructField{{
+		Name: "Profiles",
+		Type: section,
+		Tag:  tags("profiles"),
+	}}))
+
+	wrapper.Elem().Field(0).Field(0).Set(reflect.ValueOf(resource))
+
+	for _, file := range loader.files {
+		err := loader.unmarshaller(file.data, wrapper.Interface())
+		if err != nil {
+			return karma.Format(
+				err,
+				"unable to unmarshal profile %q",
+				loader.profile,
+			)
+		}
+	}
+
+	return nil
+}
+
+// pushChain adds path to the chain of files being loaded and returns an
+// error naming the whole chain if path is already in it.
+func pushChain(chain []string, path string) ([]string, error) {
+	absolute, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, item := range chain {
+		if item == absolute {
+			return nil, fmt.Errorf(
+				"config files form a cycle: %s",
+				strings.Join(push(chain[i:], absolute), " -> "),
+			)
+		}
+	}
+
+	return push(chain, absolute), nil
+}
+
+// resolveInclude expands include pattern relative to the directory of the
+// including file. Patterns with glob meta characters may match no files, but
+// plain paths must exist.
+func resolveInclude(path string, pattern string) ([]string, error) {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(filepath.Dir(path), pattern)
+	}
+
+	if !strings.ContainsAny(pattern, "*?[") {
+		return []string{pattern}, nil
+	}
+
+	matches, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, karma.Format(
+			err,
+			"invalid include pattern %q in %q",
+			pattern,
+			path,
+		)
+	}
+
+	sort.Strings(matches)
+
+	return matches, nil
+}
+
+func (loader *loader) validate(
+	value interface{},
+	parentRequired bool,
+	prefix ...string,
+) error {
+	return loader.validateValue(reflect.ValueOf(value), parentRequired, prefix)
+}
+
+// validateValue is validate for values which can not be passed as
+// interface{}, like embedded structs of unexported types.
+func (loader *loader) validateValue(
+	value reflect.Value,
+	parentRequired bool,
+	prefix []string,
+) error {
+	// Shared pointers are validated once and cycles are not followed.
+	if !loader.visit("validate", value) {
+		return nil
+	}
+
+	resource := reflect.Indirect(value)
+	switch resource.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return loader.validateItems(resource, parentRequired, prefix)
+	}
+
 	if resource.Kind() != reflect.Struct {
 		return fmt.Errorf("resource should be a struct")
 	}
 
+	err := loader.enter(prefix)
+	if err != nil {
+		return err
+	}
+
+	defer loader.leave()
+
 	resourceStruct := resource.Type()
 	for index := 0; index < resourceStruct.NumField(); index++ {
 		var (
@@ -116,44 +552,104 @@ func validate(
 			structFieldRequired = structField.Tag.Get("required") == "true"
 		)
 
+		// Fields of inline structs, including unexported embedded ones,
+		// are validated as fields of this struct.
+		if isInline(structField, loader.promotesEmbedded()) {
+			embedded := reflect.Indirect(resourceField)
+			if embedded.IsValid() {
+				err := loader.validateValue(embedded, parentRequired, prefix)
+				if err != nil {
+					return err
+				}
+			}
+
+			continue
+		}
+
 		if fieldName[0] == strings.ToLower(fieldName)[0] {
 			continue
 		}
 
+		envName := structField.Tag.Get("env")
 		if reflect.DeepEqual(
 			resourceField.Interface(),
 			reflect.Zero(resourceField.Type()).Interface(),
 		) {
-			envName := structField.Tag.Get("env")
 			if envName != "" {
 				envValue := os.Getenv(envName)
 				if envValue != "" {
 					if !resourceField.CanAddr() {
-						return fmt.Errorf(
-							"target field is not addressable %q",
-							strings.Join(
-								push(prefix, getFieldKey(structField)),
-								".",
+						return newFieldError(
+							push(prefix, getFieldKey(structField)),
+							fmt.Errorf(
+								"target field is not addressable %q",
+								strings.Join(
+									push(prefix, getFieldKey(structField)),
+									".",
+								),
 							),
 						)
 					}
 
-					err := yaml.Unmarshal(
-						[]byte(envValue),
-						resourceField.Addr().Interface(),
-					)
+					err := loader.decodeValue(resourceField, envValue)
 					if err != nil {
-						return karma.Format(
-							err,
-							"unable to unmarshal env value for field: %s",
-							strings.Join(
-								push(prefix, getFieldKey(structField)),
-								".",
+						return newFieldError(
+							push(prefix, getFieldKey(structField)),
+							karma.Format(
+								err,
+								"unable to unmarshal env value for field: %s",
+								strings.Join(
+									push(prefix, getFieldKey(structField)),
+									".",
+								),
 							),
 						)
 					}
+
+					loader.emit(Event{
+						Kind: EventEnv,
+						Path: strings.Join(
+							push(prefix, getFieldKey(structField)),
+							".",
+						),
+						Env: envName,
+					})
 				}
 			}
+		} else if envName != "" && os.Getenv(envName) != "" {
+			loader.emit(Event{
+				Kind: EventEnvIgnored,
+				Path: strings.Join(
+					push(prefix, getFieldKey(structField)),
+					".",
+				),
+				Env: envName,
+			})
+		}
+
+		if loader.shouldAllocate(resourceField, structField) {
+			allocated, err := loader.allocate(
+				resourceField,
+				structFieldRequired,
+				push(prefix, getFieldKey(structField)),
+			)
+			if err != nil {
+				return err
+			}
+
+			if allocated {
+				continue
+			}
+		}
+
+		if resourceField.Kind() == reflect.Interface {
+			err := loader.validateInterface(
+				resourceField,
+				push(prefix, getFieldKey(structField)),
+			)
+			if err != nil {
+				return err
+			}
 		}
 
 		for {
@@ -168,20 +664,28 @@ func validate(
 			resourceField = resourceField.Elem()
 		}
 
-		if resourceField.Kind() == reflect.Struct && resourceField.CanAddr() {
+		// Structs which decode themselves, like time.Time, are values
+		// rather than sections.
+		if resourceField.Kind() == reflect.Struct && resourceField.CanAddr() &&
+			!loader.isDecodable(resourceField.Type()) {
 			// Skip validation of zero-valued structs if they are not required
 			isZeroValue := reflect.DeepEqual(
 				resourceField.Interface(),
 				reflect.Zero(resourceField.Type()).Interface(),
 			)
-			if isZeroValue && !structFieldRequired {
+			// The chosen member of a oneof group is validated as if it was
+			// required, even if it is a pointer to a zero-valued struct.
+			chosen := structField.Tag.Get("oneof_group") != "" &&
+				!resource.Field(index).IsZero()
+
+			if isZeroValue && !structFieldRequired && !chosen {
 				// Skip validation for optional zero-valued structs
 				continue
 			}
 
-			err := validate(
+			err := loader.validate(
 				resourceField.Addr().Interface(),
-				structFieldRequired,
+				structFieldRequired || chosen,
 				push(prefix, getFieldKey(structField))...,
 			)
 			if err != nil {
@@ -193,97 +697,305 @@ func validate(
 			resourceField.Interface(),
 			reflect.Zero(resourceField.Type()).Interface(),
 		) {
-			defaultValue := structField.Tag.Get("default")
+			defaultValue := loader.getDefault(structField)
 			if defaultValue != "" {
 				if !resourceField.CanAddr() {
-					return fmt.Errorf(
-						"target field is not addressable %q",
-						strings.Join(
-							push(prefix, getFieldKey(structField)),
-							".",
+					return newFieldError(
+						push(prefix, getFieldKey(structField)),
+						fmt.Errorf(
+							"target field is not addressable %q",
+							strings.Join(
+								push(prefix, getFieldKey(structField)),
+								".",
+							),
 						),
 					)
 				}
 
-				err := yaml.Unmarshal(
-					[]byte(defaultValue),
-					resourceField.Addr().Interface(),
-				)
+				err := loader.decodeValue(resourceField, defaultValue)
 				if err != nil {
-					return karma.Format(
-						err,
-						"unable to unmarshal default value for field %q",
-						strings.Join(
-							push(prefix, getFieldKey(structField)),
-							".",
+					return newFieldError(
+						push(prefix, getFieldKey(structField)),
+						karma.Format(
+							err,
+							"unable to unmarshal default value for field %q",
+							strings.Join(
+								push(prefix, getFieldKey(structField)),
+								".",
+							),
 						),
 					)
 				}
+
+				loader.emit(Event{
+					Kind: EventDefault,
+					Path: strings.Join(
+						push(prefix, getFieldKey(structField)),
+						".",
+					),
+					Value: defaultValue,
+				})
 			} else if parentRequired && structFieldRequired {
-				envName := structField.Tag.Get("env")
 				additional := ""
 				if envName != "" {
 					additional = ", no value for environment variable " +
 						envName + " specified"
 				}
-				return fmt.Errorf(
-					"field %q is required, but no value specified%s",
-					strings.Join(
-						push(prefix, getFieldKey(structField)),
-						".",
+				return newFieldError(
+					push(prefix, getFieldKey(structField)),
+					fmt.Errorf(
+						"field %q is required, but no value specified%s",
+						strings.Join(
+							push(prefix, getFieldKey(structField)),
+							".",
+						),
+						additional,
 					),
-					additional,
 				)
 			}
+		} else {
+			err := loader.mergeDefault(
+				resourceField,
+				structField,
+				push(prefix, getFieldKey(structField)),
+			)
+			if err != nil {
+				return err
+			}
 		}
 
-		if resourceField.Kind() == reflect.Slice {
-			for i := 0; i < resourceField.Len(); i++ {
-				field := reflect.Indirect(resourceField.Index(i))
-				if field.Kind() == reflect.Struct {
-					err := validate(
-						field.Addr().Interface(),
-						structFieldRequired,
-						push(
-							prefix,
-							fmt.Sprintf(
-								"%s[%d]", getFieldKey(structField), i,
-							),
-						)...,
-					)
-					if err != nil {
-						return err
-					}
-				}
+		err := loader.resolvePaths(
+			resourceField,
+			structField,
+			push(prefix, getFieldKey(structField)),
+		)
+		if err != nil {
+			return err
+		}
+
+		err = validateNetwork(
+			resourceField,
+			structField,
+			push(prefix, getFieldKey(structField)),
+		)
+		if err != nil {
+			return err
+		}
+
+		err = applyDefaultKeys(
+			resourceField,
+			structField,
+			push(prefix, getFieldKey(structField)),
+		)
+		if err != nil {
+			return err
+		}
+
+		if parentRequired {
+			err := checkRequiredKeys(
+				resourceField,
+				structField,
+				push(prefix, getFieldKey(structField)),
+			)
+			if err != nil {
+				return err
 			}
 		}
 
-		if resourceField.Kind() == reflect.Map {
-			for _, key := range resourceField.MapKeys() {
-				field := resourceField.MapIndex(key)
+		switch resourceField.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			err := loader.validateItems(
+				resourceField,
+				structFieldRequired,
+				push(prefix, getFieldKey(structField)),
+			)
+			if err != nil {
+				return err
+			}
+		}
 
-				if reflect.Indirect(reflect.ValueOf(field.Interface())).Kind() == reflect.Struct {
-					err := validate(
-						field.Interface(),
-						structFieldRequired,
-						push(
-							prefix,
-							fmt.Sprintf(
-								"%s[%s]", getFieldKey(structField), key,
-							),
-						)...,
-					)
-					if err != nil {
-						return err
-					}
-				}
+		// Collections are checked after their items got defaults, so that
+		// unique also compares default values.
+		err = validateCollection(
+			resourceField,
+			structField,
+			push(prefix, getFieldKey(structField)),
+			loader.promotesEmbedded(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Groups and conditions are checked after env and default values are
+	// applied, so a field set from its env var counts as specified.
+	err = validateGroups(resource, prefix)
+	if err != nil {
+		return err
+	}
+
+	err = validateConditions(resource, prefix, loader.promotesEmbedded())
+	if err != nil {
+		return err
+	}
+
+	err = validateRules(resource, prefix, loader.promotesEmbedded())
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// validateItems validates structs held by items of the slice, array or map
+// located at prefix. Map values are not addressable, so every value is
+// copied, validated and stored back to get defaults and env values applied.
+func (loader *loader) validateItems(
+	items reflect.Value,
+	required bool,
+	prefix []string,
+) error {
+	switch items.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < items.Len(); i++ {
+			err := loader.validateItem(
+				items.Index(i),
+				required,
+				pushItem(prefix, strconv.Itoa(i)),
+			)
+			if err != nil {
+				return err
 			}
 		}
+
+	case reflect.Map:
+		for _, key := range items.MapKeys() {
+			item := reflect.New(items.Type().Elem()).Elem()
+			item.Set(items.MapIndex(key))
+
+			err := loader.validateItem(
+				item,
+				required,
+				pushItem(prefix, fmt.Sprint(key.Interface())),
+			)
+			if err != nil {
+				return err
+			}
+
+			items.SetMapIndex(key, item)
+		}
 	}
 
 	return nil
 }
 
+// validateItem validates the struct held by item directly, by pointer or in
+// an interface, or items of nested slices, arrays and maps.
+func (loader *loader) validateItem(
+	item reflect.Value,
+	required bool,
+	prefix []string,
+) error {
+	switch item.Kind() {
+	case reflect.Interface:
+		return loader.validateInterface(item, prefix)
+
+	case reflect.Ptr:
+		if reflect.Indirect(item).Kind() == reflect.Struct {
+			return loader.validate(item.Interface(), required, prefix...)
+		}
+
+	case reflect.Struct:
+		if item.CanAddr() {
+			return loader.validate(item.Addr().Interface(), required, prefix...)
+		}
+
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return loader.validateItems(item, required, prefix)
+	}
+
+	return nil
+}
+
+// reservedDefaultTags are tags with default_ prefix which are not
+// default_<profile> tags.
+var reservedDefaultTags = []string{"default_keys", "default_merge"}
+
+// getDefault returns value of default tag for the field. If a profile is
+// active and the field has default_<profile> tag, its value is used instead.
+func (loader *loader) getDefault(field reflect.StructField) string {
+	if loader.profile != "" &&
+		!inSlice(reservedDefaultTags, "default_"+loader.profile) {
+		value, ok := field.Tag.Lookup("default_" + loader.profile)
+		if ok {
+			return value
+		}
+	}
+
+	return field.Tag.Get("default")
+}
+
+// collectDefaultProfiles adds names of profiles used in default_<profile>
+// tags of the type and types nested in it to profiles.
+func collectDefaultProfiles(
+	target reflect.Type,
+	profiles map[string]struct{},
+	seen map[reflect.Type]bool,
+) {
+	for target != nil {
+		switch target.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			target = target.Elem()
+			continue
+		}
+
+		break
+	}
+
+	if target == nil || target.Kind() != reflect.Struct || seen[target] {
+		return
+	}
+
+	seen[target] = true
+
+	for i := 0; i < target.NumField(); i++ {
+		field := target.Field(i)
+
+		for _, key := range tagKeys(field.Tag) {
+			if strings.HasPrefix(key, "default_") &&
+				!inSlice(reservedDefaultTags, key) {
+				profiles[strings.TrimPrefix(key, "default_")] = struct{}{}
+			}
+		}
+
+		collectDefaultProfiles(field.Type, profiles, seen)
+	}
+}
+
+// tagKeys returns keys of the struct tag in the conventional
+// key:"value" format.
+func tagKeys(tag reflect.StructTag) []string {
+	keys := []string{}
+
+	rest := string(tag)
+	for {
+		rest = strings.TrimLeft(rest, " ")
+
+		colon := strings.Index(rest, ":\"")
+		if colon <= 0 || strings.ContainsAny(rest[:colon], " \"") {
+			return keys
+		}
+
+		value, err := strconv.QuotedPrefix(rest[colon+1:])
+		if err != nil {
+			return keys
+		}
+
+		keys = append(keys, rest[:colon])
+		rest = rest[colon+1+len(value):]
+	}
+}
+
 func getFieldKey(field reflect.StructField) string {
 	knownTags := []string{"yaml", "toml", "json"}
 	for _, tag := range knownTags {
@@ -299,6 +1011,116 @@ func getFieldKey(field reflect.StructField) string {
 	return strcase.ToSnake(field.Name)
 }
 
+// promotesEmbedded reports whether the unmarshaller promotes fields of
+// embedded structs without a key name, like toml and json do. yaml.v3
+// decodes such structs under their own key unless they are tagged with
+// yaml:",inline".
+func (loader *loader) promotesEmbedded() bool {
+	switch reflect.ValueOf(loader.unmarshaller).Pointer() {
+	case reflect.ValueOf(yaml.Unmarshal).Pointer():
+		return false
+	case reflect.ValueOf(toml.Unmarshal).Pointer(),
+		reflect.ValueOf(json.Unmarshal).Pointer():
+		return true
+	}
+
+	switch strings.ToLower(filepath.Ext(loader.path)) {
+	case ".yaml", ".yml":
+		return false
+	}
+
+	return true
+}
+
+// isInline reports whether fields of the struct field are promoted to the
+// level of the struct containing it, the way unmarshallers treat fields with
+// yaml:",inline" and, if promote is set, embedded structs without a key
+// name.
+func isInline(field reflect.StructField, promote bool) bool {
+	target := field.Type
+	if target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+
+	if target.Kind() != reflect.Struct {
+		return false
+	}
+
+	for _, tag := range []string{"yaml", "toml", "json"} {
+		parts := strings.Split(field.Tag.Get(tag), ",")
+		if inSlice(parts[1:], "inline") {
+			return true
+		}
+
+		if parts[0] != "" {
+			return false
+		}
+	}
+
+	return promote && field.Anonymous
+}
+
+// structFields returns exported fields of the struct type with fields of
+// inline structs promoted to its level; Index of a promoted field is the
+// full index sequence for FieldByIndex. Own fields go first, so they shadow
+// promoted fields with the same key.
+func structFields(
+	target reflect.Type,
+	promote bool,
+	seen ...reflect.Type,
+) []reflect.StructField {
+	if inSlice(seen, target) {
+		return nil
+	}
+
+	fields := []reflect.StructField{}
+	promoted := []reflect.StructField{}
+	for index := 0; index < target.NumField(); index++ {
+		field := target.Field(index)
+		if isInline(field, promote) {
+			inner := field.Type
+			if inner.Kind() == reflect.Ptr {
+				inner = inner.Elem()
+			}
+
+			for _, child := range structFields(inner, promote, push(seen, target)...) {
+				child.Index = append([]int{index}, child.Index...)
+				promoted = append(promoted, child)
+			}
+
+			continue
+		}
+
+		if field.PkgPath != "" {
+			continue
+		}
+
+		fields = append(fields, field)
+	}
+
+	return append(fields, promoted...)
+}
+
+// fieldByIndex returns the field of resource like FieldByIndex does, or a
+// zero value if the field is inside a nil embedded pointer.
+func fieldByIndex(resource reflect.Value, field reflect.StructField) reflect.Value {
+	value, err := resource.FieldByIndexErr(field.Index)
+	if err != nil {
+		return reflect.Zero(field.Type)
+	}
+
+	return value
+}
+
 func push[K any](prefix []K, value K) []K {
 	return append(append([]K{}, prefix...), value)
 }
+
+func inSlice[K comparable](items []K, target K) bool {
+	for _, value := range items {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -472,6 +473,123 @@ a = true
 	test.Equal(ko, resource)
 }
 
+type EmbeddedBase struct {
+	Name string `yaml:"name" json:"name" required:"true"`
+	Port int    `yaml:"port" json:"port" default:"80"`
+}
+
+type embeddedHidden struct {
+	Token string `yaml:"token" json:"token" default:"secret"`
+}
+
+func TestEmbeddedInline(t *testing.T) {
+	test := assert.New(t)
+
+	type config struct {
+		EmbeddedBase   `yaml:",inline"`
+		embeddedHidden `yaml:",inline"`
+
+		Backup EmbeddedBase `yaml:"backup" required:"true"`
+	}
+
+	path := write(`{name: main, backup: {name: b}}`)
+	defer os.Remove(path)
+
+	var cfg config
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+	test.Equal(80, cfg.Port)
+	test.Equal("secret", cfg.Token)
+	test.Equal(80, cfg.Backup.Port)
+
+	path = write(`{backup: {name: b}}`)
+	defer os.Remove(path)
+
+	err = Load(path, &config{}, yaml.Unmarshal)
+	test.EqualError(err, `field "name" is required, but no value specified`)
+}
+
+func TestEmbeddedJSON(t *testing.T) {
+	test := assert.New(t)
+
+	type config struct {
+		*EmbeddedBase
+		embeddedHidden
+	}
+
+	path := write(`{"name": "main", "tokn": "x"}`)
+	defer os.Remove(path)
+
+	var cfg config
+	err := Load(path, &cfg, json.Unmarshal, Strict(true))
+	test.EqualError(err, `unknown key "tokn", did you mean "token"?`)
+
+	path = write(`{"name": "main"}`)
+	defer os.Remove(path)
+
+	cfg = config{}
+	err = Load(path, &cfg, json.Unmarshal, Rule(`name == "main" && port == 80`))
+	test.NoError(err)
+	test.Equal("secret", cfg.Token)
+}
+
+func TestEmbeddedYAML(t *testing.T) {
+	test := assert.New(t)
+
+	type config struct {
+		EmbeddedBase
+		Listen string `yaml:"listen"`
+	}
+
+	path := write(`{embeddedbase: {name: main}, listen: ":80"}`)
+	defer os.Remove(path)
+
+	// yaml.v3 decodes embedded structs without the inline flag under
+	// their own key
+	var cfg config
+	err := Load(path, &cfg, yaml.Unmarshal, Strict(true))
+	test.NoError(err)
+	test.Equal("main", cfg.Name)
+	test.Equal(80, cfg.Port)
+
+	path = write(`{name: main}`)
+	defer os.Remove(path)
+
+	err = Load(path, &config{}, yaml.Unmarshal, Strict(true))
+	test.EqualError(err, `unknown key "name"`)
+
+	data := []byte("embeddedbase: {name: main}\n")
+
+	_, err = UpdateYAML(data, &config{}, map[string]interface{}{"name": "x"})
+	test.EqualError(err, `field "name": unknown key "name"`)
+
+	cfg = config{}
+	result, err := UpdateYAML(data, &cfg, map[string]interface{}{
+		"embedded_base.name": "x",
+	})
+	test.NoError(err)
+	test.Equal("embeddedbase: {name: x}\n", string(result))
+	test.Equal("x", cfg.Name)
+}
+
+func TestEmbeddedTOML(t *testing.T) {
+	test := assert.New(t)
+
+	type config struct {
+		EmbeddedBase
+		Listen string `toml:"listen"`
+	}
+
+	path := write("name = \"main\"\nlisten = \":80\"\n")
+	defer os.Remove(path)
+
+	var cfg config
+	err := Load(path, &cfg, Strict(true))
+	test.NoError(err)
+	test.Equal("main", cfg.Name)
+	test.Equal(80, cfg.Port)
+}
+
 func write(data string) string {
 	file, err := ioutil.TempFile(os.TempDir(), "ko_")
 	if err != nil {
@@ -632,7 +750,7 @@ func TestCheckRequired_DefaultInSubfield(t *testing.T) {
 	}
 }
 
-func TestCheckRequiredFieldsInMap_DefaultNotaddressable(t *testing.T) {
+func TestCheckRequiredFieldsInMap_DefaultNonPointer(t *testing.T) {
 	test := assert.New(t)
 
 	path := write(`
@@ -653,7 +771,8 @@ foo:
 	{
 		var cfg config
 		err := Load(path, &cfg, yaml.Unmarshal)
-		test.EqualError(err, `target field is not addressable "foo[key].bar"`)
+		test.NoError(err)
+		test.Equal("q", cfg.Foo["key"].Bar)
 	}
 }
 
@@ -686,7 +805,7 @@ foo:
 	}
 }
 
-func TestCheckRequiredFieldsInMap_EnvNotaddressable(t *testing.T) {
+func TestCheckRequiredFieldsInMap_EnvNonPointer(t *testing.T) {
 	test := assert.New(t)
 
 	path := write(`
@@ -710,7 +829,8 @@ foo:
 	{
 		var cfg config
 		err := Load(path, &cfg, yaml.Unmarshal)
-		test.EqualError(err, `target field is not addressable "foo[key].bar"`)
+		test.NoError(err)
+		test.Equal("valueA", cfg.Foo["key"].Bar)
 	}
 }
 
@@ -765,6 +885,116 @@ func TestCheckMapTypeInt(t *testing.T) {
 	}
 }
 
+func TestRootMap(t *testing.T) {
+	test := assert.New(t)
+
+	type service struct {
+		Host string `yaml:"host" required:"true"`
+		Port int    `yaml:"port" default:"80"`
+	}
+
+	path := write(`
+primary: {host: a}
+replica: {host: b, port: 8080}
+`)
+	defer os.Remove(path)
+
+	var cfg map[string]service
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+	test.Equal(
+		map[string]service{
+			"primary": {Host: "a", Port: 80},
+			"replica": {Host: "b", Port: 8080},
+		},
+		cfg,
+	)
+
+	path = write(`primary: {port: 8080}`)
+	defer os.Remove(path)
+
+	cfg = nil
+	err = Load(path, &cfg, yaml.Unmarshal)
+	test.EqualError(
+		err,
+		`field "[primary].host" is required, but no value specified`,
+	)
+}
+
+func TestRootArray(t *testing.T) {
+	test := assert.New(t)
+
+	type shard struct {
+		Addr   string `yaml:"addr" required:"true"`
+		Weight int    `yaml:"weight" default:"1"`
+	}
+
+	path := write(`[{addr: a}, {addr: b, weight: 2}]`)
+	defer os.Remove(path)
+
+	var cfg [2]shard
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+	test.Equal([2]shard{{Addr: "a", Weight: 1}, {Addr: "b", Weight: 2}}, cfg)
+
+	var shards []shard
+	path = write(`[{addr: a}, {weight: 2}]`)
+	defer os.Remove(path)
+
+	err = Load(path, &shards, yaml.Unmarshal)
+	test.EqualError(
+		err,
+		`field "[1].addr" is required, but no value specified`,
+	)
+}
+
+func TestCheckRequiredFieldsInArray(t *testing.T) {
+	test := assert.New(t)
+
+	type shard struct {
+		Addr   string `yaml:"addr" required:"true"`
+		Weight int    `yaml:"weight" default:"1"`
+	}
+
+	type config struct {
+		Shards [3]shard            `yaml:"shards" required:"true"`
+		Groups map[string][]*shard `yaml:"groups" required:"true"`
+	}
+
+	path := write(`
+shards: [{addr: a}, {addr: b}, {addr: c}]
+groups: {main: [{addr: d}]}
+`)
+	defer os.Remove(path)
+
+	var cfg config
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+	test.Equal(1, cfg.Shards[2].Weight)
+	test.Equal(1, cfg.Groups["main"][0].Weight)
+
+	path = write(`shards: [{addr: a}, {addr: b}, {weight: 3}]`)
+	defer os.Remove(path)
+
+	err = Load(path, &config{}, yaml.Unmarshal)
+	test.EqualError(
+		err,
+		`field "shards[2].addr" is required, but no value specified`,
+	)
+
+	path = write(`
+shards: [{addr: a}, {addr: b}, {addr: c}]
+groups: {main: [{addr: d}, {}]}
+`)
+	defer os.Remove(path)
+
+	err = Load(path, &config{}, yaml.Unmarshal)
+	test.EqualError(
+		err,
+		`field "groups[main][1].addr" is required, but no value specified`,
+	)
+}
+
 func TestOptionalStructWithRequiredFields(t *testing.T) {
 	// Test case: Optional struct containing required fields should not
 	// validate required fields when the optional struct is not provided
@@ -855,3 +1085,450 @@ auth:
 		test.Equal("key123", cfg.Auth.Credentials.APIKey)
 	})
 }
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = ioutil.WriteFile(path, []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestInclude(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"main.yaml": `
+include: ["db.yaml", "routes/*.yaml"]
+name: main
+db:
+  port: 5433
+`,
+		"db.yaml": `
+db:
+  host: localhost
+  port: 5432
+`,
+		"routes/a.yaml": `
+routes:
+  a: /a
+`,
+		"routes/b.yaml": `
+routes:
+  b: /b
+`,
+	})
+
+	type config struct {
+		Name string `yaml:"name"`
+		DB   struct {
+			Host string `yaml:"host" required:"true"`
+			Port int    `yaml:"port"`
+		} `yaml:"db" required:"true"`
+		Routes map[string]string `yaml:"routes"`
+	}
+
+	var cfg config
+	test.NoError(
+		Load(filepath.Join(dir, "main.yaml"), &cfg, yaml.Unmarshal),
+	)
+
+	test.Equal("main", cfg.Name)
+	test.Equal("localhost", cfg.DB.Host)
+	test.Equal(5433, cfg.DB.Port)
+	test.Equal(map[string]string{"a": "/a", "b": "/b"}, cfg.Routes)
+}
+
+func TestInclude_Nested(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"main.toml": `
+include = ["conf.d/server.toml"]
+`,
+		"conf.d/server.toml": `
+include = ["listen.toml"]
+host = "example.com"
+`,
+		"conf.d/listen.toml": `
+listen = ":80"
+`,
+	})
+
+	type config struct {
+		Host   string
+		Listen string
+	}
+
+	var cfg config
+	test.NoError(Load(filepath.Join(dir, "main.toml"), &cfg))
+	test.Equal("example.com", cfg.Host)
+	test.Equal(":80", cfg.Listen)
+}
+
+func TestInclude_Missing(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"main.yaml": `include: ["db.yaml"]`,
+	})
+
+	type config struct {
+		Name string `yaml:"name"`
+	}
+
+	var cfg config
+	err := Load(
+		filepath.Join(dir, "main.yaml"), &cfg, yaml.Unmarshal,
+		RequireFile(false),
+	)
+	test.Error(err)
+	test.Contains(err.Error(), "db.yaml")
+}
+
+func TestInclude_OwnField(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+include: foo
+extends: [a, b]
+name: app
+`)
+	defer os.Remove(path)
+
+	var cfg struct {
+		Include string   `yaml:"include"`
+		Extends []string `yaml:"extends"`
+		Name    string   `yaml:"name"`
+	}
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+	test.Equal("foo", cfg.Include)
+	test.Equal([]string{"a", "b"}, cfg.Extends)
+	test.Equal("app", cfg.Name)
+}
+
+func TestInclude_MapRoot(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+extends: foo
+include: bar
+profiles: x
+`)
+	defer os.Remove(path)
+
+	var cfg map[string]string
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+	test.Equal(
+		map[string]string{"extends": "foo", "include": "bar", "profiles": "x"},
+		cfg,
+	)
+}
+
+func TestInclude_Errors(t *testing.T) {
+	type config struct {
+		Name string `yaml:"name"`
+	}
+
+	{
+		test := assert.New(t)
+
+		path := write("name: [app\n")
+		defer os.Remove(path)
+
+		var cfg config
+		err := Load(path, &cfg, yaml.Unmarshal)
+		test.Error(err)
+		test.NotContains(err.Error(), "directives")
+		test.Contains(err.Error(), "yaml:")
+	}
+
+	{
+		test := assert.New(t)
+
+		path := write("include: db.yaml\nname: app\n")
+		defer os.Remove(path)
+
+		var cfg config
+		err := Load(path, &cfg, yaml.Unmarshal)
+		test.Error(err)
+		test.Contains(
+			err.Error(),
+			"unable to read extends and include directives in",
+		)
+	}
+}
+
+func TestInclude_Cycle(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"a.yaml": `include: ["b.yaml"]`,
+		"b.yaml": `include: ["c.yaml"]`,
+		"c.yaml": `include: ["b.yaml"]`,
+	})
+
+	type config struct {
+		Name string `yaml:"name"`
+	}
+
+	var cfg config
+	err := Load(filepath.Join(dir, "a.yaml"), &cfg, yaml.Unmarshal)
+	test.Error(err)
+	test.Contains(
+		err.Error(),
+		"config files form a cycle: "+
+			filepath.Join(dir, "b.yaml")+" -> "+
+			filepath.Join(dir, "c.yaml")+" -> "+
+			filepath.Join(dir, "b.yaml"),
+	)
+}
+
+func TestExtends(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"base.toml": `
+listen = ":80"
+log_level = "info"
+
+[db]
+host = "db.internal"
+port = 5432
+`,
+		"staging.toml": `
+extends = "base.toml"
+log_level = "debug"
+`,
+		"staging-eu.toml": `
+extends = "staging.toml"
+
+[db]
+host = "db.eu.internal"
+`,
+	})
+
+	type config struct {
+		Listen   string `toml:"listen"`
+		LogLevel string `toml:"log_level"`
+		DB       struct {
+			Host string `toml:"host"`
+			Port int    `toml:"port"`
+		} `toml:"db"`
+		Timeout string `toml:"timeout" default:"5s"`
+	}
+
+	var cfg config
+	test.NoError(Load(filepath.Join(dir, "staging-eu.toml"), &cfg))
+	test.Equal(":80", cfg.Listen)
+	test.Equal("debug", cfg.LogLevel)
+	test.Equal("db.eu.internal", cfg.DB.Host)
+	test.Equal(5432, cfg.DB.Port)
+	test.Equal("5s", cfg.Timeout)
+}
+
+func TestExtends_Cycle(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"a.toml": `extends = "b.toml"`,
+		"b.toml": `extends = "a.toml"`,
+	})
+
+	type config struct {
+		Name string
+	}
+
+	var cfg config
+	err := Load(filepath.Join(dir, "a.toml"), &cfg)
+	test.Error(err)
+	test.Contains(
+		err.Error(),
+		"config files form a cycle: "+
+			filepath.Join(dir, "a.toml")+" -> "+
+			filepath.Join(dir, "b.toml")+" -> "+
+			filepath.Join(dir, "a.toml"),
+	)
+}
+
+func TestProfile(t *testing.T) {
+	type config struct {
+		Listen   string `toml:"listen"`
+		LogLevel string `toml:"log_level"`
+		DB       struct {
+			Host string `toml:"host"`
+			Port int    `toml:"port"`
+		} `toml:"db"`
+	}
+
+	path := write(`
+listen = ":80"
+log_level = "info"
+
+[db]
+host = "localhost"
+port = 5432
+
+[profiles.dev]
+log_level = "debug"
+
+[profiles.prod.db]
+host = "db.internal"
+`)
+	defer os.Remove(path)
+
+	t.Run("no profile", func(t *testing.T) {
+		test := assert.New(t)
+
+		var cfg config
+		test.NoError(Load(path, &cfg))
+		test.Equal("info", cfg.LogLevel)
+		test.Equal("localhost", cfg.DB.Host)
+	})
+
+	t.Run("profile option", func(t *testing.T) {
+		test := assert.New(t)
+
+		var cfg config
+		test.NoError(Load(path, &cfg, Profile("prod")))
+		test.Equal(":80", cfg.Listen)
+		test.Equal("info", cfg.LogLevel)
+		test.Equal("db.internal", cfg.DB.Host)
+		test.Equal(5432, cfg.DB.Port)
+	})
+
+	t.Run("profile env", func(t *testing.T) {
+		test := assert.New(t)
+
+		os.Setenv("APP_PROFILE", "dev")
+		defer os.Unsetenv("APP_PROFILE")
+
+		var cfg config
+		test.NoError(Load(path, &cfg, ProfileEnv("APP_PROFILE")))
+		test.Equal("debug", cfg.LogLevel)
+		test.Equal("localhost", cfg.DB.Host)
+
+		cfg = config{}
+		test.NoError(
+			Load(path, &cfg, ProfileEnv("APP_PROFILE"), Profile("prod")),
+		)
+		test.Equal("info", cfg.LogLevel)
+	})
+
+	t.Run("unknown profile", func(t *testing.T) {
+		test := assert.New(t)
+
+		var cfg config
+		test.EqualError(
+			Load(path, &cfg, Profile("stage")),
+			"unknown profile \"stage\", available profiles: dev, prod\n"+
+				"└─ profile: stage",
+		)
+	})
+}
+
+func TestProfile_InBaseFile(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": `
+log_level: info
+profiles:
+  prod:
+    log_level: warning
+`,
+		"app.yaml": `
+extends: base.yaml
+log_level: debug
+`,
+	})
+
+	type config struct {
+		LogLevel string `yaml:"log_level"`
+		Token    string `yaml:"token" required:"true"`
+	}
+
+	var cfg config
+	err := Load(
+		filepath.Join(dir, "app.yaml"), &cfg, yaml.Unmarshal, Profile("prod"),
+	)
+	test.EqualError(
+		err,
+		"field \"token\" is required, but no value specified\n"+
+			"└─ profile: prod",
+	)
+	test.Equal("warning", cfg.LogLevel)
+}
+
+func TestProfileDefault(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`listen = ":443"`)
+	defer os.Remove(path)
+
+	type config struct {
+		Listen   string `toml:"listen" default:":8080"`
+		LogLevel string `toml:"log_level" default:"debug" default_prod:"info"`
+		DB       struct {
+			Pool int `toml:"pool" default:"1" default_prod:"16" default_stage:"4"`
+		} `toml:"db" required:"true"`
+	}
+
+	{
+		var cfg config
+		test.NoError(Load(path, &cfg))
+		test.Equal(":443", cfg.Listen)
+		test.Equal("debug", cfg.LogLevel)
+		test.Equal(1, cfg.DB.Pool)
+	}
+
+	{
+		var cfg config
+		test.NoError(Load(path, &cfg, Profile("prod")))
+		test.Equal(":443", cfg.Listen)
+		test.Equal("info", cfg.LogLevel)
+		test.Equal(16, cfg.DB.Pool)
+	}
+
+	{
+		var cfg config
+		test.NoError(
+			Load("/nonexistent.toml", &cfg, RequireFile(false), Profile("stage")),
+		)
+		test.Equal(":8080", cfg.Listen)
+		test.Equal(4, cfg.DB.Pool)
+	}
+
+	{
+		var cfg config
+		test.EqualError(
+			Load(path, &cfg, Profile("dev")),
+			"unknown profile \"dev\", available profiles: prod, stage\n"+
+				"└─ profile: dev",
+		)
+	}
+
+	{
+		var cfg struct {
+			Listen string `toml:"listen"`
+		}
+		test.EqualError(
+			Load(path, &cfg, Profile("dev")),
+			"unknown profile \"dev\", no profiles are declared\n"+
+				"└─ profile: dev",
+		)
+	}
+}
@@ -0,0 +1,145 @@
+package ko
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// applyDefaultKeys adds entries listed in default_keys tag which are
+// missing in the map. New entries are zero values, or pointers to zero
+// structs, so that defaults of their fields are applied along with the
+// rest of the items.
+func applyDefaultKeys(
+	value reflect.Value,
+	structField reflect.StructField,
+	prefix []string,
+) error {
+	keys := splitKeys(structField.Tag.Get("default_keys"))
+	if len(keys) == 0 || value.Kind() != reflect.Map {
+		return nil
+	}
+
+	field := strings.Join(prefix, ".")
+
+	if value.IsNil() {
+		if !value.CanSet() {
+			return newFieldError(prefix, fmt.Errorf(
+				"target field is not addressable %q",
+				field,
+			))
+		}
+
+		value.Set(reflect.MakeMap(value.Type()))
+	}
+
+	elem := value.Type().Elem()
+	for _, name := range keys {
+		key, err := decodeKey(value.Type().Key(), name)
+		if err != nil {
+			return newFieldError(prefix, fmt.Errorf(
+				"field %q: unable to decode key %q: %s",
+				field,
+				name,
+				err,
+			))
+		}
+
+		if value.MapIndex(key).IsValid() {
+			continue
+		}
+
+		item := reflect.Zero(elem)
+		switch {
+		case elem.Kind() == reflect.Ptr:
+			item = reflect.New(elem.Elem())
+		case elem.Kind() == reflect.Interface:
+			return newFieldError(prefix, fmt.Errorf(
+				"field %q: default_keys is not supported for values of %s",
+				field,
+				elem,
+			))
+		}
+
+		value.SetMapIndex(key, item)
+	}
+
+	return nil
+}
+
+// checkRequiredKeys reports the first key listed in required_keys tag which
+// is missing in the map.
+func checkRequiredKeys(
+	value reflect.Value,
+	structField reflect.StructField,
+	prefix []string,
+) error {
+	keys := splitKeys(structField.Tag.Get("required_keys"))
+	if len(keys) == 0 {
+		return nil
+	}
+
+	field := strings.Join(prefix, ".")
+
+	if value.Kind() == reflect.Ptr {
+		value = reflect.Zero(value.Type().Elem())
+	}
+
+	if value.Kind() != reflect.Map {
+		return newFieldError(prefix, fmt.Errorf(
+			"field %q: required_keys is supported only for maps",
+			field,
+		))
+	}
+
+	for _, name := range keys {
+		key, err := decodeKey(value.Type().Key(), name)
+		if err != nil {
+			return newFieldError(prefix, fmt.Errorf(
+				"field %q: unable to decode key %q: %s",
+				field,
+				name,
+				err,
+			))
+		}
+
+		if !value.MapIndex(key).IsValid() {
+			return newFieldError(pushItem(prefix, name), fmt.Errorf(
+				"field %q is required, but no value specified",
+				strings.Join(pushItem(prefix, name), "."),
+			))
+		}
+	}
+
+	return nil
+}
+
+// decodeKey decodes the map key written in a tag, keys of other types than
+// string are written the way they are in YAML.
+func decodeKey(target reflect.Type, name string) (reflect.Value, error) {
+	key := reflect.New(target).Elem()
+	if target.Kind() == reflect.String {
+		key.SetString(name)
+		return key, nil
+	}
+
+	err := yaml.Unmarshal([]byte(name), key.Addr().Interface())
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	return key, nil
+}
+
+func splitKeys(tag string) []string {
+	keys := []string{}
+	for _, key := range strings.Split(tag, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+
+	return keys
+}
@@ -0,0 +1,113 @@
+package ko
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type mapKeysDatabase struct {
+	Host string `yaml:"host" default:"localhost"`
+	Port int    `yaml:"port" default:"5432"`
+}
+
+type mapKeysConfig struct {
+	Databases map[string]*mapKeysDatabase `yaml:"databases" required_keys:"primary" default_keys:"replica"`
+	Weights   map[int]mapKeysDatabase     `yaml:"weights" default_keys:"1"`
+}
+
+func TestMapKeys(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+databases:
+  primary: {host: db1}
+`)
+	defer os.Remove(path)
+
+	var cfg mapKeysConfig
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+
+	test.Equal(
+		map[string]*mapKeysDatabase{
+			"primary": {Host: "db1", Port: 5432},
+			"replica": {Host: "localhost", Port: 5432},
+		},
+		cfg.Databases,
+	)
+	test.Equal(
+		map[int]mapKeysDatabase{1: {Host: "localhost", Port: 5432}},
+		cfg.Weights,
+	)
+}
+
+func TestMapKeys_KeepsFileEntries(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+databases:
+  primary: {host: db1}
+  replica: {host: db2, port: 6432}
+`)
+	defer os.Remove(path)
+
+	var cfg mapKeysConfig
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+
+	test.Equal(&mapKeysDatabase{Host: "db2", Port: 6432}, cfg.Databases["replica"])
+}
+
+func TestMapKeys_Required(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`databases: {replica: {host: db2}}`)
+	defer os.Remove(path)
+
+	var cfg mapKeysConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "databases[primary]" is required, but no value specified`,
+	)
+}
+
+func TestMapKeys_InvalidKey(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`{}`)
+	defer os.Remove(path)
+
+	var cfg struct {
+		Weights map[int]string `yaml:"weights" default_keys:"one"`
+	}
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.Error(err)
+	test.Contains(err.Error(), `field "weights": unable to decode key "one"`)
+}
+
+func TestMapKeys_NotProfile(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+databases: {primary: {}}
+profiles: {keys: {}}
+`)
+	defer os.Remove(path)
+
+	var cfg mapKeysConfig
+	err := Load(path, &cfg, yaml.Unmarshal, Profile("keys"))
+	test.NoError(err)
+	test.Contains(cfg.Databases, "replica")
+
+	path = write(`databases: {primary: {}}`)
+	defer os.Remove(path)
+
+	err = Load(path, &mapKeysConfig{}, yaml.Unmarshal, Profile("keys"))
+	test.EqualError(
+		err,
+		"unknown profile \"keys\", no profiles are declared\n└─ profile: keys",
+	)
+}
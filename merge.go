@@ -0,0 +1,97 @@
+package ko
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/reconquest/karma-go"
+)
+
+// mergeDefault merges the default value of the map field with
+// default_merge:"true" into the map loaded from files. Keys from files
+// win, struct values present in both are merged field by field.
+func (loader *loader) mergeDefault(
+	value reflect.Value,
+	structField reflect.StructField,
+	prefix []string,
+) error {
+	if structField.Tag.Get("default_merge") != "true" {
+		return nil
+	}
+
+	field := strings.Join(prefix, ".")
+
+	if value.Kind() != reflect.Map {
+		return newFieldError(prefix, fmt.Errorf(
+			"field %q: default_merge is supported only for maps",
+			field,
+		))
+	}
+
+	defaultValue := loader.getDefault(structField)
+	if defaultValue == "" {
+		return nil
+	}
+
+	defaults := reflect.New(value.Type()).Elem()
+	err := loader.decodeValue(defaults, defaultValue)
+	if err != nil {
+		return newFieldError(prefix, karma.Format(
+			err,
+			"unable to unmarshal default value for field %q",
+			field,
+		))
+	}
+
+	mergeValue(value, defaults)
+
+	loader.emit(Event{
+		Kind:  EventDefault,
+		Path:  field,
+		Value: defaultValue,
+	})
+
+	return nil
+}
+
+// mergeValue fills target with parts of defaults it lacks: missing map
+// entries, nil pointers and zero struct fields. Values set in target are
+// kept, maps and structs in both are merged recursively.
+func mergeValue(target, defaults reflect.Value) {
+	if !target.CanSet() {
+		return
+	}
+
+	if target.IsZero() {
+		target.Set(defaults)
+		return
+	}
+
+	switch target.Kind() {
+	case reflect.Map:
+		for _, key := range defaults.MapKeys() {
+			item := target.MapIndex(key)
+			if !item.IsValid() {
+				target.SetMapIndex(key, defaults.MapIndex(key))
+				continue
+			}
+
+			merged := reflect.New(item.Type()).Elem()
+			merged.Set(item)
+			mergeValue(merged, defaults.MapIndex(key))
+
+			target.SetMapIndex(key, merged)
+		}
+
+	case reflect.Ptr:
+		if !defaults.IsNil() {
+			mergeValue(target.Elem(), defaults.Elem())
+		}
+
+	case reflect.Struct:
+		for i := 0; i < target.NumField(); i++ {
+			mergeValue(target.Field(i), defaults.Field(i))
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package ko
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type mergeLimit struct {
+	Rate  int `yaml:"rate"`
+	Burst int `yaml:"burst"`
+}
+
+type mergeConfig struct {
+	Labels map[string]string         `yaml:"labels" default:"{env: prod, team: core}" default_merge:"true"`
+	Limits map[string]*mergeLimit    `yaml:"limits" default:"{api: {rate: 10, burst: 20}, web: {rate: 5}}" default_merge:"true"`
+	Nested map[string]map[string]int `yaml:"nested" default:"{a: {x: 1, y: 2}}" default_merge:"true"`
+	Plain  map[string]string         `yaml:"plain" default:"{env: prod}"`
+}
+
+func TestDefaultMerge(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+labels: {team: edge, zone: eu}
+limits:
+  api: {rate: 100}
+nested: {a: {y: 3}, b: {z: 4}}
+plain: {zone: eu}
+`)
+	defer os.Remove(path)
+
+	var cfg mergeConfig
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+
+	test.Equal(
+		map[string]string{"env": "prod", "team": "edge", "zone": "eu"},
+		cfg.Labels,
+	)
+	test.Equal(
+		map[string]*mergeLimit{
+			"api": {Rate: 100, Burst: 20},
+			"web": {Rate: 5},
+		},
+		cfg.Limits,
+	)
+	test.Equal(
+		map[string]map[string]int{
+			"a": {"x": 1, "y": 3},
+			"b": {"z": 4},
+		},
+		cfg.Nested,
+	)
+	test.Equal(map[string]string{"zone": "eu"}, cfg.Plain)
+}
+
+func TestDefaultMerge_Empty(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`{}`)
+	defer os.Remove(path)
+
+	var cfg mergeConfig
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+
+	test.Equal(map[string]string{"env": "prod", "team": "core"}, cfg.Labels)
+	test.Equal(&mergeLimit{Rate: 10, Burst: 20}, cfg.Limits["api"])
+}
+
+func TestDefaultMerge_NotMap(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`name: a`)
+	defer os.Remove(path)
+
+	var cfg struct {
+		Name string `yaml:"name" default:"b" default_merge:"true"`
+	}
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.EqualError(err, `field "name": default_merge is supported only for maps`)
+}
+
+func TestDefaultMerge_NotProfile(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+labels: {zone: eu}
+profiles: {merge: {}}
+`)
+	defer os.Remove(path)
+
+	var cfg mergeConfig
+	err := Load(path, &cfg, yaml.Unmarshal, Profile("merge"))
+	test.NoError(err)
+	test.Equal(
+		map[string]string{"env": "prod", "team": "core", "zone": "eu"},
+		cfg.Labels,
+	)
+}
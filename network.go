@@ -0,0 +1,183 @@
+package ko
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// networkValidators are values of validate tag checked by validateNetwork.
+var networkValidators = []string{"url", "hostport", "ip", "cidr", "port"}
+
+// validateNetwork checks the value of the field against validate and
+// url_scheme tags. Zero values are not checked, that is what required is
+// for. Items of slices and arrays are checked one by one. Other names in
+// the validate tag are left to other validation libraries sharing it, like
+// "required,min=1" of go-playground/validator.
+func validateNetwork(
+	value reflect.Value,
+	structField reflect.StructField,
+	prefix []string,
+) error {
+	validators := []string{}
+	for _, validator := range strings.Split(structField.Tag.Get("validate"), ",") {
+		validator = strings.TrimSpace(validator)
+		if validator == "" {
+			continue
+		}
+
+		if !inSlice(networkValidators, validator) {
+			continue
+		}
+
+		validators = append(validators, validator)
+	}
+
+	schemes := []string{}
+	for _, scheme := range strings.Split(structField.Tag.Get("url_scheme"), ",") {
+		if scheme = strings.TrimSpace(scheme); scheme != "" {
+			schemes = append(schemes, scheme)
+		}
+	}
+
+	if len(schemes) > 0 && !inSlice(validators, "url") {
+		validators = append(validators, "url")
+	}
+
+	if len(validators) == 0 {
+		return nil
+	}
+
+	return checkNetwork(value, validators, schemes, prefix)
+}
+
+func checkNetwork(
+	value reflect.Value,
+	validators []string,
+	schemes []string,
+	prefix []string,
+) error {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+
+		value = value.Elem()
+	}
+
+	_, stringer := value.Interface().(fmt.Stringer)
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		if stringer {
+			break
+		}
+
+		for i := 0; i < value.Len(); i++ {
+			err := checkNetwork(
+				value.Index(i),
+				validators,
+				schemes,
+				pushItem(prefix, strconv.Itoa(i)),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if value.IsZero() {
+		return nil
+	}
+
+	path := strings.Join(prefix, ".")
+
+	var text string
+	switch typed := value.Interface().(type) {
+	case url.URL:
+		text = typed.String()
+	case fmt.Stringer:
+		text = typed.String()
+	default:
+		text = fmt.Sprint(typed)
+	}
+
+	for _, validator := range validators {
+		var err error
+		switch validator {
+		case "url":
+			err = checkURL(path, text, schemes)
+
+		case "hostport":
+			host, port, splitErr := net.SplitHostPort(text)
+			if splitErr != nil || strings.ContainsAny(host, " /") ||
+				!isPort(port) {
+				err = fmt.Errorf("field %q must be host:port, got %q", path, text)
+			}
+
+		case "ip":
+			if net.ParseIP(text) == nil {
+				err = fmt.Errorf(
+					"field %q must be an IP address, got %q",
+					path,
+					text,
+				)
+			}
+
+		case "cidr":
+			_, _, parseErr := net.ParseCIDR(text)
+			if parseErr != nil {
+				err = fmt.Errorf(
+					"field %q must be a CIDR network like 10.0.0.0/8, got %q",
+					path,
+					text,
+				)
+			}
+
+		case "port":
+			if !isPort(text) {
+				err = fmt.Errorf(
+					"field %q must be a port number from 1 to 65535, got %s",
+					path,
+					text,
+				)
+			}
+		}
+
+		if err != nil {
+			return newFieldError(prefix, err)
+		}
+	}
+
+	return nil
+}
+
+func checkURL(path string, text string, schemes []string) error {
+	parsed, err := url.Parse(text)
+	if err != nil || parsed.Scheme == "" ||
+		parsed.Host == "" && parsed.Opaque == "" {
+		return fmt.Errorf("field %q must be a URL, got %q", path, text)
+	}
+
+	if len(schemes) > 0 && !inSlice(schemes, strings.ToLower(parsed.Scheme)) {
+		return fmt.Errorf(
+			"field %q must be a URL with scheme %s, got %q",
+			path,
+			strings.Join(schemes, " or "),
+			text,
+		)
+	}
+
+	return nil
+}
+
+func isPort(text string) bool {
+	port, err := strconv.Atoi(text)
+
+	return err == nil && port >= 1 && port <= 65535
+}
@@ -0,0 +1,155 @@
+package ko
+
+import (
+	"net"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type networkConfig struct {
+	Endpoint string   `yaml:"endpoint" validate:"url" url_scheme:"https,grpc"`
+	Proxy    string   `yaml:"proxy" validate:"url"`
+	Listen   string   `yaml:"listen" validate:"hostport" default:":8080"`
+	Peers    []string `yaml:"peers" validate:"hostport"`
+	Bind     net.IP   `yaml:"bind" validate:"ip"`
+	Address  string   `yaml:"address" validate:"ip"`
+	Allow    []string `yaml:"allow" validate:"cidr"`
+	Port     int      `yaml:"port" validate:"port"`
+	Backends []struct {
+		URL string `yaml:"url" url_scheme:"http"`
+	} `yaml:"backends"`
+}
+
+func TestNetwork(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+endpoint: grpc://api.example.com:443
+proxy: http://proxy:3128
+peers: ["a:1", "[::1]:2"]
+bind: 10.0.0.1
+address: "::1"
+allow: [10.0.0.0/8, "fd00::/8"]
+port: 8080
+backends: [{url: "http://b"}]
+`)
+	defer os.Remove(path)
+
+	var cfg networkConfig
+	test.NoError(Load(path, &cfg, yaml.Unmarshal))
+	test.Equal(":8080", cfg.Listen)
+}
+
+func TestNetwork_URL(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`endpoint: api.example.com`)
+	defer os.Remove(path)
+
+	var cfg networkConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "endpoint" must be a URL, got "api.example.com"`,
+	)
+}
+
+func TestNetwork_URLScheme(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`endpoint: http://api.example.com`)
+	defer os.Remove(path)
+
+	var cfg networkConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "endpoint" must be a URL with scheme https or grpc, `+
+			`got "http://api.example.com"`,
+	)
+
+	path = write(`backends: [{url: "http://a"}, {url: "https://b"}]`)
+	defer os.Remove(path)
+
+	test.EqualError(
+		Load(path, &networkConfig{}, yaml.Unmarshal),
+		`field "backends[1].url" must be a URL with scheme http, got "https://b"`,
+	)
+}
+
+func TestNetwork_HostPort(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`peers: ["a:1", "b"]`)
+	defer os.Remove(path)
+
+	var cfg networkConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "peers[1]" must be host:port, got "b"`,
+	)
+
+	path = write(`peers: ["a:99999"]`)
+	defer os.Remove(path)
+
+	test.EqualError(
+		Load(path, &networkConfig{}, yaml.Unmarshal),
+		`field "peers[0]" must be host:port, got "a:99999"`,
+	)
+}
+
+func TestNetwork_IP(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`address: 10.0.0.256`)
+	defer os.Remove(path)
+
+	var cfg networkConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "address" must be an IP address, got "10.0.0.256"`,
+	)
+}
+
+func TestNetwork_CIDR(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`allow: [10.0.0.0/33]`)
+	defer os.Remove(path)
+
+	var cfg networkConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "allow[0]" must be a CIDR network like 10.0.0.0/8, `+
+			`got "10.0.0.0/33"`,
+	)
+}
+
+func TestNetwork_Port(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`port: 70000`)
+	defer os.Remove(path)
+
+	var cfg networkConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal),
+		`field "port" must be a port number from 1 to 65535, got 70000`,
+	)
+}
+
+func TestNetwork_OtherValidators(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`{host: a, addr: b}`)
+	defer os.Remove(path)
+
+	var cfg struct {
+		Host string `yaml:"host" validate:"required,min=1"`
+		Addr string `yaml:"addr" validate:"required,hostport"`
+	}
+
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.EqualError(err, `field "addr" must be host:port, got "b"`)
+}
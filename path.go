@@ -0,0 +1,215 @@
+package ko
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// pathOptions are values of path tag besides "true".
+var pathOptions = []string{"file", "dir", "exists", "writable"}
+
+// resolvePaths expands ~ and makes relative paths in the field absolute
+// against the directory of the config file which sets the field, then
+// checks them against options of the path tag. Values that do not come
+// from a file, like env and default values, are resolved against the
+// directory of the file passed to Load.
+func (loader *loader) resolvePaths(
+	value reflect.Value,
+	structField reflect.StructField,
+	prefix []string,
+) error {
+	tag := structField.Tag.Get("path")
+	if tag == "" || tag == "false" {
+		return nil
+	}
+
+	options := []string{}
+	for _, option := range strings.Split(tag, ",") {
+		option = strings.TrimSpace(option)
+		if option == "true" || option == "" {
+			continue
+		}
+
+		if !inSlice(pathOptions, option) {
+			return newFieldError(prefix, fmt.Errorf(
+				"field %q: unknown path option %q",
+				strings.Join(prefix, "."),
+				option,
+			))
+		}
+
+		options = append(options, option)
+	}
+
+	return loader.resolvePath(value, options, prefix)
+}
+
+func (loader *loader) resolvePath(
+	value reflect.Value,
+	options []string,
+	prefix []string,
+) error {
+	for value.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			err := loader.resolvePath(
+				value.Index(i),
+				options,
+				pushItem(prefix, strconv.Itoa(i)),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+
+	case reflect.String:
+	default:
+		return nil
+	}
+
+	path := value.String()
+	if path == "" {
+		return nil
+	}
+
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return newFieldError(prefix, fmt.Errorf(
+				"field %q: unable to expand %q: %s",
+				strings.Join(prefix, "."),
+				path,
+				err,
+			))
+		}
+
+		path = filepath.Join(home, path[1:])
+	}
+
+	if !filepath.IsAbs(path) && loader.path != "" {
+		path = filepath.Join(filepath.Dir(loader.fileOf(prefix)), path)
+
+		absolute, err := filepath.Abs(path)
+		if err == nil {
+			path = absolute
+		}
+	}
+
+	if value.CanSet() {
+		value.SetString(path)
+	}
+
+	return checkPath(path, options, prefix)
+}
+
+// fileOf returns the config file which sets the field at path.
+func (loader *loader) fileOf(prefix []string) string {
+	if loader.keyPositions == nil {
+		loader.keyPositions = loader.positions(loader.resourceType)
+	}
+
+	if position, ok := loader.keyPositions[strings.Join(prefix, ".")]; ok {
+		return position.File
+	}
+
+	return loader.path
+}
+
+func checkPath(path string, options []string, prefix []string) error {
+	field := strings.Join(prefix, ".")
+
+	info, err := os.Stat(path)
+	switch {
+	case os.IsNotExist(err):
+		if inSlice(options, "exists") {
+			return newFieldError(prefix, fmt.Errorf(
+				"field %q: path %q does not exist",
+				field,
+				path,
+			))
+		}
+
+		if inSlice(options, "writable") && !isWritableDir(filepath.Dir(path)) {
+			return newFieldError(prefix, fmt.Errorf(
+				"field %q: path %q can not be created",
+				field,
+				path,
+			))
+		}
+
+		return nil
+
+	case err != nil:
+		return newFieldError(prefix, fmt.Errorf(
+			"field %q: unable to stat %q: %s",
+			field,
+			path,
+			err,
+		))
+	}
+
+	if inSlice(options, "file") && !info.Mode().IsRegular() {
+		return newFieldError(prefix, fmt.Errorf(
+			"field %q: path %q is not a file",
+			field,
+			path,
+		))
+	}
+
+	if inSlice(options, "dir") && !info.IsDir() {
+		return newFieldError(prefix, fmt.Errorf(
+			"field %q: path %q is not a directory",
+			field,
+			path,
+		))
+	}
+
+	if inSlice(options, "writable") {
+		writable := isWritableDir(path)
+		if !info.IsDir() {
+			file, err := os.OpenFile(path, os.O_WRONLY, 0)
+			if err == nil {
+				file.Close()
+			}
+
+			writable = err == nil
+		}
+
+		if !writable {
+			return newFieldError(prefix, fmt.Errorf(
+				"field %q: path %q is not writable",
+				field,
+				path,
+			))
+		}
+	}
+
+	return nil
+}
+
+// isWritableDir reports whether a file can be created in the directory.
+func isWritableDir(path string) bool {
+	file, err := os.CreateTemp(path, ".ko-")
+	if err != nil {
+		return false
+	}
+
+	file.Close()
+	os.Remove(file.Name())
+
+	return true
+}
@@ -0,0 +1,153 @@
+package ko
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type pathConfig struct {
+	Cert    string   `yaml:"cert" path:"file,exists"`
+	Data    string   `yaml:"data" path:"dir,writable"`
+	Log     *string  `yaml:"log" path:"writable"`
+	Plugins []string `yaml:"plugins" path:"true"`
+	Cache   string   `yaml:"cache" path:"true" default:"cache"`
+	Raw     string   `yaml:"raw"`
+	DB      pathDB   `yaml:"db"`
+}
+
+type pathDB struct {
+	Dump string `yaml:"dump" path:"true"`
+}
+
+func TestPath(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"app.yaml": `
+include: [conf.d/db.yaml]
+cert: tls/cert.pem
+data: var
+log: app.log
+plugins: [plugins/a.so, /opt/b.so, ~/c.so]
+raw: relative
+`,
+		"conf.d/db.yaml": `db: {dump: dump.sql}`,
+		"tls/cert.pem":   "",
+		"var/.keep":      "",
+	})
+
+	var cfg pathConfig
+	err := Load(filepath.Join(dir, "app.yaml"), &cfg, yaml.Unmarshal)
+	test.NoError(err)
+
+	home, err := os.UserHomeDir()
+	test.NoError(err)
+
+	test.Equal(filepath.Join(dir, "tls/cert.pem"), cfg.Cert)
+	test.Equal(filepath.Join(dir, "var"), cfg.Data)
+	test.Equal(filepath.Join(dir, "app.log"), *cfg.Log)
+	test.Equal(
+		[]string{
+			filepath.Join(dir, "plugins/a.so"),
+			"/opt/b.so",
+			filepath.Join(home, "c.so"),
+		},
+		cfg.Plugins,
+	)
+	test.Equal(filepath.Join(dir, "cache"), cfg.Cache)
+	test.Equal("relative", cfg.Raw)
+	test.Equal(filepath.Join(dir, "conf.d/dump.sql"), cfg.DB.Dump)
+}
+
+func TestPath_Exists(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{"app.yaml": `cert: missing.pem`})
+
+	var cfg pathConfig
+	test.EqualError(
+		Load(filepath.Join(dir, "app.yaml"), &cfg, yaml.Unmarshal),
+		`field "cert": path "`+filepath.Join(dir, "missing.pem")+
+			`" does not exist`,
+	)
+}
+
+func TestPath_File(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"app.yaml":  `cert: var`,
+		"var/.keep": "",
+	})
+
+	var cfg pathConfig
+	test.EqualError(
+		Load(filepath.Join(dir, "app.yaml"), &cfg, yaml.Unmarshal),
+		`field "cert": path "`+filepath.Join(dir, "var")+`" is not a file`,
+	)
+}
+
+func TestPath_Dir(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"app.yaml": `data: cert.pem`,
+		"cert.pem": "",
+	})
+
+	var cfg pathConfig
+	test.EqualError(
+		Load(filepath.Join(dir, "app.yaml"), &cfg, yaml.Unmarshal),
+		`field "data": path "`+filepath.Join(dir, "cert.pem")+
+			`" is not a directory`,
+	)
+}
+
+func TestPath_CanNotBeCreated(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{"app.yaml": `log: missing/app.log`})
+
+	var cfg pathConfig
+	test.EqualError(
+		Load(filepath.Join(dir, "app.yaml"), &cfg, yaml.Unmarshal),
+		`field "log": path "`+filepath.Join(dir, "missing/app.log")+
+			`" can not be created`,
+	)
+}
+
+func TestPath_NotWritable(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root can write anywhere")
+	}
+
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{"app.yaml": `data: var`})
+
+	test.NoError(os.Mkdir(filepath.Join(dir, "var"), 0555))
+
+	var cfg pathConfig
+	err := Load(filepath.Join(dir, "app.yaml"), &cfg, yaml.Unmarshal)
+	test.EqualError(
+		err,
+		`field "data": path "`+filepath.Join(dir, "var")+`" is not writable`,
+	)
+}
+
+func TestPath_UnknownOption(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`file: a`)
+	defer os.Remove(path)
+
+	var cfg struct {
+		File string `yaml:"file" path:"file,readable"`
+	}
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.EqualError(err, `field "file": unknown path option "readable"`)
+}
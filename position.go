@@ -0,0 +1,521 @@
+package ko
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Position is a location in a configuration file.
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+// IsValid reports whether the position is known.
+func (position Position) IsValid() bool {
+	return position.Line > 0
+}
+
+func (position Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", position.File, position.Line, position.Column)
+}
+
+// FieldError is an error related to a particular field of the config. Load
+// returns it for validation errors; use errors.As to get it. Position points
+// to the key of the field in the file it was loaded from or, if the key is
+// absent, to the closest key that encloses it. It is not set for formats that
+// do not expose positions.
+type FieldError struct {
+	Path     string
+	Position Position
+	Err      error
+}
+
+func newFieldError(path []string, err error) *FieldError {
+	return &FieldError{Path: strings.Join(path, "."), Err: err}
+}
+
+func (err *FieldError) Error() string {
+	return err.Err.Error()
+}
+
+func (err *FieldError) Unwrap() error {
+	return err.Err
+}
+
+// FormatError returns text of the error prefixed with file:line:column and
+// followed by a snippet of the file around the position for every
+// FieldError found in err. Other errors are returned as is.
+func FormatError(err error) string {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		lines := []string{}
+		for _, err := range joined.Unwrap() {
+			lines = append(lines, FormatError(err))
+		}
+
+		return strings.Join(lines, "\n")
+	}
+
+	var fieldError *FieldError
+	if !errors.As(err, &fieldError) || !fieldError.Position.IsValid() {
+		return err.Error()
+	}
+
+	position := fieldError.Position
+
+	result := position.String() + ": " + err.Error()
+
+	data, readErr := ioutil.ReadFile(position.File)
+	if readErr != nil {
+		return result
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	first := position.Line - 2
+	if first < 1 {
+		first = 1
+	}
+
+	last := position.Line + 2
+	if last > len(lines) {
+		last = len(lines)
+	}
+
+	width := len(strconv.Itoa(last))
+	for number := first; number <= last; number++ {
+		result += fmt.Sprintf(
+			"\n%*d | %s",
+			width,
+			number,
+			lines[number-1],
+		)
+
+		if number == position.Line {
+			result += fmt.Sprintf(
+				"\n%*s | %s^",
+				width,
+				"",
+				strings.Repeat(" ", position.Column-1),
+			)
+		}
+	}
+
+	return result
+}
+
+// locate sets positions of all FieldErrors found in err.
+func (loader *loader) locate(err error, resource interface{}) {
+	var positions map[string]Position
+
+	var walk func(err error)
+	walk = func(err error) {
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, err := range joined.Unwrap() {
+				walk(err)
+			}
+
+			return
+		}
+
+		var fieldError *FieldError
+		if !errors.As(err, &fieldError) || fieldError.Position.IsValid() {
+			return
+		}
+
+		if positions == nil {
+			positions = loader.positions(reflect.TypeOf(resource))
+		}
+
+		for path := fieldError.Path; ; path = parentPath(path) {
+			if position, ok := positions[path]; ok {
+				fieldError.Position = position
+				return
+			}
+
+			if path == "" {
+				return
+			}
+		}
+	}
+
+	walk(err)
+}
+
+// positions returns positions of keys in all loaded files indexed by field
+// path. Files loaded later win, the same way their values do.
+func (loader *loader) positions(resource reflect.Type) map[string]Position {
+	positions := map[string]Position{}
+
+	for _, file := range loader.files {
+		var keys []keyPosition
+		switch loader.format(file.path) {
+		case "yaml":
+			keys = yamlPositions(file.data)
+		case "toml":
+			keys = tomlPositions(file.data)
+		}
+
+		for _, key := range keys {
+			path := translatePath(resource, key.keys, loader.promotesEmbedded())
+			key.position.File = file.path
+
+			positions[path] = key.position
+		}
+	}
+
+	return positions
+}
+
+// format guesses format of the file by its extension or, if it is not
+// conclusive, by the unmarshaller. JSON is parsed as YAML, which is a
+// superset of it.
+func (loader *loader) format(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml", ".json":
+		return "yaml"
+	case ".toml":
+		return "toml"
+	}
+
+	unmarshaller := reflect.ValueOf(loader.unmarshaller).Pointer()
+	switch unmarshaller {
+	case reflect.ValueOf(yaml.Unmarshal).Pointer():
+		return "yaml"
+	case reflect.ValueOf(toml.Unmarshal).Pointer():
+		return "toml"
+	}
+
+	return ""
+}
+
+type keyPosition struct {
+	keys     []pathKey
+	position Position
+}
+
+func yamlPositions(data []byte) []keyPosition {
+	var document yaml.Node
+	err := yaml.Unmarshal(data, &document)
+	if err != nil || len(document.Content) == 0 {
+		return nil
+	}
+
+	return yamlNodePositions(document.Content[0], nil)
+}
+
+func yamlNodePositions(node *yaml.Node, keys []pathKey) []keyPosition {
+	positions := []keyPosition{}
+
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			path := push(keys, pathKey{key: key.Value})
+
+			positions = append(positions, keyPosition{
+				keys:     path,
+				position: Position{Line: key.Line, Column: key.Column},
+			})
+
+			positions = append(
+				positions,
+				yamlNodePositions(node.Content[i+1], path)...,
+			)
+		}
+
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			path := push(keys, pathKey{index: i, item: true})
+
+			positions = append(positions, keyPosition{
+				keys:     path,
+				position: Position{Line: item.Line, Column: item.Column},
+			})
+
+			positions = append(positions, yamlNodePositions(item, path)...)
+		}
+	}
+
+	return positions
+}
+
+// tomlPositions finds positions of table headers and keys in TOML document.
+// It does not parse values, so keys inside inline tables are not found.
+func tomlPositions(data []byte) []keyPosition {
+	var (
+		positions = []keyPosition{}
+		table     = []pathKey{}
+		tables    = map[string]int{}
+		depth     = 0
+		multiline = ""
+	)
+
+	for number, line := range strings.Split(string(data), "\n") {
+		if multiline != "" {
+			if strings.Count(line, multiline)%2 == 1 {
+				multiline = ""
+			}
+
+			continue
+		}
+
+		if depth > 0 {
+			depth += tomlDepth(line)
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		column := len(line) - len(strings.TrimLeft(line, " \t")) + 1
+
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+
+		case strings.HasPrefix(trimmed, "[["):
+			keys, _ := splitTOMLKey(strings.TrimPrefix(trimmed, "[["))
+			if len(keys) == 0 {
+				continue
+			}
+
+			table = tomlTablePath(tables, keys[:len(keys)-1])
+			table = push(table, pathKey{key: keys[len(keys)-1]})
+
+			index, ok := tables[tomlPathString(table)]
+			if ok {
+				index++
+			}
+
+			tables[tomlPathString(table)] = index
+			table = push(table, pathKey{index: index, item: true})
+
+		case strings.HasPrefix(trimmed, "["):
+			keys, _ := splitTOMLKey(strings.TrimPrefix(trimmed, "["))
+			table = tomlTablePath(tables, keys)
+
+		default:
+			keys, rest := splitTOMLKey(trimmed)
+			if len(keys) == 0 || !strings.HasPrefix(rest, "=") {
+				continue
+			}
+
+			path := append([]pathKey{}, table...)
+			for _, key := range keys {
+				path = append(path, pathKey{key: key})
+			}
+
+			positions = append(positions, keyPosition{
+				keys:     path,
+				position: Position{Line: number + 1, Column: column},
+			})
+
+			value := strings.TrimSpace(strings.TrimPrefix(rest, "="))
+			for _, quotes := range []string{`"""`, `'''`} {
+				if strings.HasPrefix(value, quotes) &&
+					strings.Count(value, quotes)%2 == 1 {
+					multiline = quotes
+				}
+			}
+
+			depth = tomlDepth(value)
+
+			continue
+		}
+
+		positions = append(positions, keyPosition{
+			keys:     table,
+			position: Position{Line: number + 1, Column: column},
+		})
+	}
+
+	return positions
+}
+
+// tomlTablePath builds path of the table header, inserting indexes of the
+// last items of arrays of tables it goes through.
+func tomlTablePath(tables map[string]int, keys []string) []pathKey {
+	path := []pathKey{}
+	for _, key := range keys {
+		path = append(path, pathKey{key: key})
+
+		if index, ok := tables[tomlPathString(path)]; ok {
+			path = append(path, pathKey{index: index, item: true})
+		}
+	}
+
+	return path
+}
+
+func tomlPathString(path []pathKey) string {
+	parts := []string{}
+	for _, key := range path {
+		if key.item {
+			parts = append(parts, "["+strconv.Itoa(key.index)+"]")
+		} else {
+			parts = append(parts, strconv.Quote(key.key))
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// splitTOMLKey parses dotted key at the beginning of line and returns its
+// parts and the rest of the line.
+func splitTOMLKey(line string) ([]string, string) {
+	keys := []string{}
+
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if line == "" {
+			return keys, line
+		}
+
+		var key string
+		switch line[0] {
+		case '"', '\'':
+			end := strings.IndexByte(line[1:], line[0])
+			if end < 0 {
+				return keys, line
+			}
+
+			key = line[1 : end+1]
+			if line[0] == '"' {
+				if unquoted, err := strconv.Unquote(line[:end+2]); err == nil {
+					key = unquoted
+				}
+			}
+
+			line = line[end+2:]
+
+		default:
+			end := strings.IndexFunc(line, func(char rune) bool {
+				return !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' ||
+					char >= '0' && char <= '9' || char == '_' || char == '-')
+			})
+			if end == 0 {
+				return keys, line
+			}
+
+			if end < 0 {
+				end = len(line)
+			}
+
+			key = line[:end]
+			line = line[end:]
+		}
+
+		keys = append(keys, key)
+
+		line = strings.TrimLeft(line, " \t")
+		if !strings.HasPrefix(line, ".") {
+			return keys, line
+		}
+
+		line = line[1:]
+	}
+}
+
+// tomlDepth returns difference between opening and closing brackets in line
+// outside of strings and comments.
+func tomlDepth(line string) int {
+	depth := 0
+	quote := byte(0)
+
+	for i := 0; i < len(line); i++ {
+		char := line[i]
+		switch {
+		case quote != 0:
+			if char == '\\' && quote == '"' {
+				i++
+			} else if char == quote {
+				quote = 0
+			}
+		case char == '"' || char == '\'':
+			quote = char
+		case char == '#':
+			return depth
+		case char == '[' || char == '{':
+			depth++
+		case char == ']' || char == '}':
+			depth--
+		}
+	}
+
+	return depth
+}
+
+// translatePath converts keys as they are spelled in a file to the field
+// path in ko notation. Keys that do not match any field are kept as is.
+func translatePath(
+	target reflect.Type,
+	keys []pathKey,
+	promote bool,
+) string {
+	path := []string{}
+
+	for _, key := range keys {
+		for target != nil && target.Kind() == reflect.Ptr {
+			target = target.Elem()
+		}
+
+		if key.item {
+			path = pushItem(path, strconv.Itoa(key.index))
+			if target != nil && (target.Kind() == reflect.Slice ||
+				target.Kind() == reflect.Array) {
+				target = target.Elem()
+			} else {
+				target = nil
+			}
+
+			continue
+		}
+
+		switch {
+		case target != nil && target.Kind() == reflect.Struct:
+			field, ok := matchField(target, key.key, promote)
+			if ok {
+				path = push(path, getFieldKey(field))
+				target = field.Type
+			} else {
+				path = push(path, key.key)
+				target = nil
+			}
+
+		case target != nil && target.Kind() == reflect.Map:
+			path = pushItem(path, key.key)
+			target = target.Elem()
+
+		default:
+			path = push(path, key.key)
+			target = nil
+		}
+	}
+
+	return strings.Join(path, ".")
+}
+
+// parentPath returns path of the field that encloses the field at path.
+func parentPath(path string) string {
+	if strings.HasSuffix(path, "]") {
+		start := strings.LastIndexByte(path, '[')
+		if start >= 0 {
+			return path[:start]
+		}
+	}
+
+	end := strings.LastIndexByte(path, '.')
+	if end < 0 {
+		return ""
+	}
+
+	return path[:end]
+}
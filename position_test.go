@@ -0,0 +1,159 @@
+package ko
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type positionConfig struct {
+	Listen string `yaml:"listen" toml:"listen" required:"true"`
+	Routes []struct {
+		Path    string `yaml:"path" toml:"path" required:"true"`
+		Backend string `yaml:"backend" toml:"backend" required:"true"`
+	} `yaml:"routes" toml:"routes" required:"true"`
+}
+
+func TestPosition_YAML(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"config.yaml": `listen: ":80"
+routes:
+  - path: /
+    backend: web
+  - path: /api
+`,
+	})
+
+	path := filepath.Join(dir, "config.yaml")
+
+	var cfg positionConfig
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.EqualError(
+		err,
+		`field "routes[1].backend" is required, but no value specified`,
+	)
+
+	var fieldError *FieldError
+	if test.True(errors.As(err, &fieldError)) {
+		test.Equal("routes[1].backend", fieldError.Path)
+		test.Equal(Position{File: path, Line: 5, Column: 5}, fieldError.Position)
+	}
+
+	test.Equal(
+		path+`:5:5: field "routes[1].backend" is required, but no value specified
+3 |   - path: /
+4 |     backend: web
+5 |   - path: /api
+  |     ^`,
+		FormatError(err),
+	)
+}
+
+func TestPosition_TOML(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"config.toml": `listen = ":80"
+
+[[routes]]
+path = "/"
+backend = "web"
+
+[[routes]]
+path = "/api"
+`,
+	})
+
+	path := filepath.Join(dir, "config.toml")
+
+	var cfg positionConfig
+	err := Load(path, &cfg)
+
+	var fieldError *FieldError
+	if test.True(errors.As(err, &fieldError)) {
+		test.Equal("routes[1].backend", fieldError.Path)
+		test.Equal(Position{File: path, Line: 7, Column: 1}, fieldError.Position)
+	}
+}
+
+func TestPosition_Strict(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": `listen: ":80"
+routes:
+  - path: /
+    backend: web
+`,
+		"app.yaml": `extends: base.yaml
+listne: ":8080"
+`,
+	})
+
+	var cfg positionConfig
+	err := Load(
+		filepath.Join(dir, "app.yaml"), &cfg, yaml.Unmarshal, Strict(true),
+	)
+
+	test.Equal(
+		filepath.Join(dir, "app.yaml")+
+			`:2:1: unknown key "listne", did you mean "listen"?
+1 | extends: base.yaml
+2 | listne: ":8080"
+  | ^`,
+		FormatError(err),
+	)
+}
+
+func TestTOMLPositions(t *testing.T) {
+	test := assert.New(t)
+
+	positions := tomlPositions([]byte(`
+title = "x"
+description = """
+key = "not a key"
+"""
+ports = [
+  8080,
+  8081,
+]
+
+[server]
+host = "localhost"
+"quoted key".value = 1
+
+[[server.routes]]
+path = "/"
+
+[[server.routes]]
+path = "/api"
+
+[server.routes.backend]
+name = "api"
+`))
+
+	paths := map[string]int{}
+	for _, position := range positions {
+		paths[tomlPathString(position.keys)] = position.position.Line
+	}
+
+	test.Equal(map[string]int{
+		`"title"`:                                2,
+		`"description"`:                          3,
+		`"ports"`:                                6,
+		`"server"`:                               11,
+		`"server"."host"`:                        12,
+		`"server"."quoted key"."value"`:          13,
+		`"server"."routes".[0]`:                  15,
+		`"server"."routes".[0]."path"`:           16,
+		`"server"."routes".[1]`:                  18,
+		`"server"."routes".[1]."path"`:           19,
+		`"server"."routes".[1]."backend"`:        21,
+		`"server"."routes".[1]."backend"."name"`: 22,
+	}, paths)
+}
@@ -0,0 +1,537 @@
+package ko
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+type (
+	// Registry is an option for Load method which maps values of a
+	// discriminator key to concrete types, so sections like
+	// {type: "s3", bucket: ...} can be decoded into interface fields:
+	//
+	//	ko.Registry{
+	//	    Key: "type",
+	//	    Types: map[string]interface{}{
+	//	        "s3":    &S3Output{},
+	//	        "kafka": &KafkaOutput{},
+	//	    },
+	//	}
+	//
+	// An interface field is decoded by the registry if any of its types
+	// implements the interface. Values in Types are used only for their
+	// types: a pointer makes the field hold a pointer, anything else makes
+	// it hold a value. Decoded structs get defaults, env values and
+	// required checks like any other struct. Several registries with
+	// different keys or types can be passed.
+	Registry struct {
+		// Key is the discriminator key, "type" if empty.
+		Key string
+
+		Types map[string]interface{}
+	}
+)
+
+func (registry Registry) key() string {
+	if registry.Key == "" {
+		return "type"
+	}
+
+	return registry.Key
+}
+
+// implementations returns sorted names of registered types implementing
+// the interface.
+func (registry Registry) implementations(target reflect.Type) []string {
+	names := []string{}
+	for name, value := range registry.Types {
+		if reflect.TypeOf(value).Implements(target) {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// isRegistered reports whether the interface type is decoded by one of
+// registries.
+func (loader *loader) isRegistered(target reflect.Type) bool {
+	if target.Kind() != reflect.Interface || target.NumMethod() == 0 {
+		return false
+	}
+
+	for _, registry := range loader.registries {
+		if len(registry.implementations(target)) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+var emptyInterface = reflect.TypeOf((*interface{})(nil)).Elem()
+
+// shadowType returns the type which is the same as target, but with
+// registered interfaces replaced by interface{}, so unmarshallers decode
+// their sections into generic data instead of failing. Unexported fields
+// are dropped from structs that have to be rebuilt.
+func (loader *loader) shadowType(target reflect.Type, seen ...reflect.Type) reflect.Type {
+	if loader.isRegistered(target) {
+		return emptyInterface
+	}
+
+	switch target.Kind() {
+	case reflect.Ptr:
+		return reflect.PtrTo(loader.shadowType(target.Elem(), seen...))
+	case reflect.Slice:
+		return reflect.SliceOf(loader.shadowType(target.Elem(), seen...))
+	case reflect.Array:
+		return reflect.ArrayOf(
+			target.Len(),
+			loader.shadowType(target.Elem(), seen...),
+		)
+	case reflect.Map:
+		return reflect.MapOf(
+			target.Key(),
+			loader.shadowType(target.Elem(), seen...),
+		)
+	case reflect.Struct:
+		// Self-referencing types can not be rebuilt with StructOf.
+		if hasCustomUnmarshaller(target) || inSlice(seen, target) {
+			return target
+		}
+
+		changed := false
+		fields := []reflect.StructField{}
+		for index := 0; index < target.NumField(); index++ {
+			field := target.Field(index)
+			if field.PkgPath != "" {
+				changed = true
+				continue
+			}
+
+			shadow := loader.shadowType(field.Type, push(seen, target)...)
+			if shadow != field.Type {
+				changed = true
+			}
+
+			fields = append(fields, reflect.StructField{
+				Name:      field.Name,
+				Type:      shadow,
+				Tag:       field.Tag,
+				Anonymous: field.Anonymous,
+			})
+		}
+
+		if changed && loader.hasRegistered(target) {
+			return reflect.StructOf(fields)
+		}
+	}
+
+	return target
+}
+
+// hasRegistered reports whether the type contains registered interfaces.
+func (loader *loader) hasRegistered(target reflect.Type, seen ...reflect.Type) bool {
+	if loader.isRegistered(target) {
+		return true
+	}
+
+	switch target.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return loader.hasRegistered(target.Elem(), seen...)
+	case reflect.Struct:
+		if hasCustomUnmarshaller(target) || inSlice(seen, target) {
+			return false
+		}
+
+		for index := 0; index < target.NumField(); index++ {
+			field := target.Field(index)
+			if field.PkgPath == "" &&
+				loader.hasRegistered(field.Type, push(seen, target)...) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// assign copies source to target of the same or of the shadow type,
+// decoding generic data of registered interfaces on the way.
+func (loader *loader) assign(
+	source reflect.Value,
+	target reflect.Value,
+	prefix []string,
+) error {
+	if source.Type() == target.Type() {
+		target.Set(source)
+		return nil
+	}
+
+	if source.Kind() == reflect.Interface || target.Kind() == reflect.Interface {
+		if source.Kind() == reflect.Interface {
+			if source.IsNil() {
+				target.Set(reflect.Zero(target.Type()))
+				return nil
+			}
+
+			source = source.Elem()
+		}
+
+		if source.Type().AssignableTo(target.Type()) {
+			target.Set(source)
+			return nil
+		}
+
+		value, err := loader.decodeInterface(
+			source.Interface(),
+			target.Type(),
+			prefix,
+		)
+		if err != nil {
+			return err
+		}
+
+		target.Set(value)
+
+		return nil
+	}
+
+	switch source.Kind() {
+	case reflect.Ptr:
+		if source.IsNil() {
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		}
+
+		// Pointers shared between fields stay shared in the copy, which
+		// also stops on cycles.
+		key := visitKey{
+			pass:    "assign",
+			pointer: source.Pointer(),
+			target:  target.Type(),
+		}
+		if value, ok := loader.assigned[key]; ok {
+			target.Set(value)
+			return nil
+		}
+
+		if loader.assigned == nil {
+			loader.assigned = map[visitKey]reflect.Value{}
+		}
+
+		value := reflect.New(target.Type().Elem())
+		loader.assigned[key] = value
+
+		err := loader.assign(source.Elem(), value.Elem(), prefix)
+		if err != nil {
+			return err
+		}
+
+		target.Set(value)
+
+	case reflect.Struct:
+		for index := 0; index < target.NumField(); index++ {
+			field := target.Type().Field(index)
+			if field.PkgPath != "" {
+				continue
+			}
+
+			err := loader.assign(
+				source.FieldByName(field.Name),
+				target.Field(index),
+				push(prefix, getFieldKey(field)),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Slice, reflect.Array:
+		if source.Kind() == reflect.Slice {
+			if source.IsNil() {
+				target.Set(reflect.Zero(target.Type()))
+				return nil
+			}
+
+			target.Set(
+				reflect.MakeSlice(target.Type(), source.Len(), source.Len()),
+			)
+		}
+
+		for i := 0; i < source.Len(); i++ {
+			err := loader.assign(
+				source.Index(i),
+				target.Index(i),
+				pushItem(prefix, fmt.Sprint(i)),
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+	case reflect.Map:
+		if source.IsNil() {
+			target.Set(reflect.Zero(target.Type()))
+			return nil
+		}
+
+		target.Set(reflect.MakeMap(target.Type()))
+		for _, key := range source.MapKeys() {
+			value := reflect.New(target.Type().Elem()).Elem()
+			err := loader.assign(
+				source.MapIndex(key),
+				value,
+				pushItem(prefix, fmt.Sprint(key.Interface())),
+			)
+			if err != nil {
+				return err
+			}
+
+			target.SetMapIndex(key, value)
+		}
+
+	default:
+		return newFieldError(prefix, fmt.Errorf(
+			"field %q: unable to convert %s to %s",
+			strings.Join(prefix, "."),
+			source.Type(),
+			target.Type(),
+		))
+	}
+
+	return nil
+}
+
+// decodeInterface decodes generic data of a section into the registered
+// type chosen by its discriminator key.
+func (loader *loader) decodeInterface(
+	data interface{},
+	target reflect.Type,
+	prefix []string,
+) (reflect.Value, error) {
+	path := strings.Join(prefix, ".")
+
+	mapping, ok := data.(map[string]interface{})
+	if !ok {
+		return reflect.Value{}, newFieldError(prefix, fmt.Errorf(
+			"field %q: unable to decode %T into %s, mapping expected",
+			path,
+			data,
+			target,
+		))
+	}
+
+	for _, registry := range loader.registries {
+		names := registry.implementations(target)
+		if len(names) == 0 {
+			continue
+		}
+
+		name, ok := mapping[registry.key()]
+		if !ok {
+			return reflect.Value{}, newFieldError(prefix, fmt.Errorf(
+				"field %q: key %q is required, available types: %s",
+				path,
+				registry.key(),
+				strings.Join(names, ", "),
+			))
+		}
+
+		if !inSlice(names, fmt.Sprint(name)) {
+			return reflect.Value{}, newFieldError(prefix, fmt.Errorf(
+				"field %q: unknown type %q, available types: %s",
+				path,
+				fmt.Sprint(name),
+				strings.Join(names, ", "),
+			))
+		}
+
+		concrete := reflect.TypeOf(registry.Types[fmt.Sprint(name)])
+
+		base := concrete
+		if base.Kind() == reflect.Ptr {
+			base = base.Elem()
+		}
+
+		shadow := reflect.New(loader.shadowType(base))
+		err := decodeGeneric(
+			normalizeKeys(shadow.Elem().Type(), mapping, loader.promotesEmbedded()),
+			shadow.Elem(),
+		)
+		if err != nil {
+			return reflect.Value{}, newFieldError(prefix, fmt.Errorf(
+				"field %q: unable to decode type %q: %s",
+				path,
+				fmt.Sprint(name),
+				err,
+			))
+		}
+
+		value := reflect.New(base)
+		err = loader.assign(shadow.Elem(), value.Elem(), prefix)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		if concrete.Kind() == reflect.Ptr {
+			return value, nil
+		}
+
+		return value.Elem(), nil
+	}
+
+	return reflect.Value{}, newFieldError(prefix, fmt.Errorf(
+		"field %q: no registered types implement %s",
+		path,
+		target,
+	))
+}
+
+// normalizeKeys renames keys of generic data to the yaml names of fields
+// of target, so data decoded by any unmarshaller can be stored with
+// decodeGeneric. Fields of embedded structs promoted by the unmarshaller
+// are nested under the key yaml.v3 expects for the embedded struct.
+func normalizeKeys(
+	target reflect.Type,
+	data interface{},
+	promote bool,
+) interface{} {
+	for target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+
+	if hasCustomUnmarshaller(target) {
+		return data
+	}
+
+	switch target.Kind() {
+	case reflect.Struct:
+		mapping, ok := data.(map[string]interface{})
+		if !ok {
+			return data
+		}
+
+		result := map[string]interface{}{}
+		for key, value := range mapping {
+			field, ok := matchField(target, key, promote)
+			if !ok {
+				result[key] = value
+				continue
+			}
+
+			path := yamlPath(target, field)
+
+			nested := result
+			for _, parent := range path[:len(path)-1] {
+				child, ok := nested[parent].(map[string]interface{})
+				if !ok {
+					child = map[string]interface{}{}
+					nested[parent] = child
+				}
+
+				nested = child
+			}
+
+			nested[path[len(path)-1]] = normalizeKeys(field.Type, value, promote)
+		}
+
+		return result
+
+	case reflect.Map:
+		mapping, ok := data.(map[string]interface{})
+		if !ok {
+			return data
+		}
+
+		result := map[string]interface{}{}
+		for key, value := range mapping {
+			result[key] = normalizeKeys(target.Elem(), value, promote)
+		}
+
+		return result
+
+	case reflect.Slice, reflect.Array:
+		items := reflect.ValueOf(data)
+		if items.Kind() != reflect.Slice {
+			return data
+		}
+
+		result := []interface{}{}
+		for i := 0; i < items.Len(); i++ {
+			result = append(
+				result,
+				normalizeKeys(
+					target.Elem(),
+					items.Index(i).Interface(),
+					promote,
+				),
+			)
+		}
+
+		return result
+	}
+
+	return data
+}
+
+// yamlPath returns keys of the field of target found by structFields as
+// yaml.v3 expects them: a field of an embedded struct is nested under the
+// key of the struct, unless the struct is tagged with yaml:",inline".
+func yamlPath(target reflect.Type, field reflect.StructField) []string {
+	path := []string{}
+	for _, index := range field.Index[:len(field.Index)-1] {
+		for target.Kind() == reflect.Ptr {
+			target = target.Elem()
+		}
+
+		embedded := target.Field(index)
+		if !isInline(embedded, false) {
+			path = append(path, getYAMLKey(embedded))
+		}
+
+		target = embedded.Type
+	}
+
+	return append(path, getYAMLKey(field))
+}
+
+// validateInterface validates the struct held by an interface value. The
+// struct is a section present in configuration, so its required fields are
+// checked. Structs held by value are not addressable, so they are
+// validated on a copy which is stored back.
+func (loader *loader) validateInterface(value reflect.Value, prefix []string) error {
+	if value.IsNil() {
+		return nil
+	}
+
+	item := value.Elem()
+	if reflect.Indirect(item).Kind() != reflect.Struct {
+		return nil
+	}
+
+	if item.Kind() == reflect.Ptr {
+		return loader.validate(item.Interface(), true, prefix...)
+	}
+
+	if !value.CanSet() {
+		return nil
+	}
+
+	copied := reflect.New(item.Type())
+	copied.Elem().Set(item)
+
+	err := loader.validate(copied.Interface(), true, prefix...)
+	if err != nil {
+		return err
+	}
+
+	value.Set(copied.Elem())
+
+	return nil
+}
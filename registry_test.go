@@ -0,0 +1,202 @@
+package ko
+
+import (
+	"os"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type registryOutput interface {
+	Name() string
+}
+
+type registryS3 struct {
+	Bucket string `yaml:"bucket" toml:"bucket" required:"true"`
+	Region string `yaml:"region" toml:"region" default:"us-east-1"`
+}
+
+func (*registryS3) Name() string { return "s3" }
+
+type registryKafka struct {
+	Brokers []string `yaml:"brokers" toml:"brokers"`
+}
+
+func (registryKafka) Name() string { return "kafka" }
+
+type registryConfig struct {
+	Output  registryOutput            `yaml:"output" toml:"output"`
+	Outputs []registryOutput          `yaml:"outputs" toml:"outputs"`
+	Named   map[string]registryOutput `yaml:"named" toml:"named"`
+}
+
+var testRegistry = Registry{
+	Types: map[string]interface{}{
+		"s3":    &registryS3{},
+		"kafka": registryKafka{},
+	},
+}
+
+func TestRegistry(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+output: {type: s3, bucket: logs}
+outputs:
+  - {type: kafka, brokers: [a, b]}
+  - {type: s3, bucket: archive, region: eu-west-1}
+named:
+  main: {type: s3, bucket: main}
+`)
+	defer os.Remove(path)
+
+	var cfg registryConfig
+	err := Load(path, &cfg, yaml.Unmarshal, testRegistry)
+	test.NoError(err)
+
+	test.Equal(&registryS3{Bucket: "logs", Region: "us-east-1"}, cfg.Output)
+	test.Equal(
+		[]registryOutput{
+			registryKafka{Brokers: []string{"a", "b"}},
+			&registryS3{Bucket: "archive", Region: "eu-west-1"},
+		},
+		cfg.Outputs,
+	)
+	test.Equal(
+		map[string]registryOutput{
+			"main": &registryS3{Bucket: "main", Region: "us-east-1"},
+		},
+		cfg.Named,
+	)
+}
+
+func TestRegistry_TOML(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+[output]
+type = "kafka"
+brokers = ["a"]
+
+[[outputs]]
+type = "s3"
+bucket = "logs"
+`)
+	defer os.Remove(path)
+
+	var cfg registryConfig
+	err := Load(path, &cfg, toml.Unmarshal, testRegistry)
+	test.NoError(err)
+
+	test.Equal(registryKafka{Brokers: []string{"a"}}, cfg.Output)
+	test.Equal(
+		[]registryOutput{&registryS3{Bucket: "logs", Region: "us-east-1"}},
+		cfg.Outputs,
+	)
+}
+
+func TestRegistry_UnknownType(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`output: {type: gcs}`)
+	defer os.Remove(path)
+
+	var cfg registryConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal, testRegistry),
+		`field "output": unknown type "gcs", available types: kafka, s3`,
+	)
+}
+
+func TestRegistry_MissingKey(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`outputs: [{bucket: logs}]`)
+	defer os.Remove(path)
+
+	var cfg registryConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal, testRegistry),
+		`field "outputs[0]": key "type" is required, available types: kafka, s3`,
+	)
+}
+
+func TestRegistry_NotMapping(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`output: s3`)
+	defer os.Remove(path)
+
+	var cfg registryConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal, testRegistry),
+		`field "output": unable to decode string into ko.registryOutput, `+
+			`mapping expected`,
+	)
+}
+
+func TestRegistry_Required(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`outputs: [{type: s3}]`)
+	defer os.Remove(path)
+
+	var cfg registryConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal, testRegistry),
+		`field "outputs[0].bucket" is required, but no value specified`,
+	)
+}
+
+func TestRegistry_KeepsPresetValues(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`outputs: [{type: kafka}]`)
+	defer os.Remove(path)
+
+	cfg := registryConfig{Output: &registryS3{Bucket: "preset"}}
+	err := Load(
+		path,
+		&cfg,
+		yaml.Unmarshal,
+		Registry{Key: "type", Types: testRegistry.Types},
+	)
+	test.NoError(err)
+
+	test.Equal(&registryS3{Bucket: "preset", Region: "us-east-1"}, cfg.Output)
+	test.Equal([]registryOutput{registryKafka{}}, cfg.Outputs)
+}
+
+type registryFile struct {
+	EmbeddedBase
+	Path string `toml:"path"`
+}
+
+func (registryFile) Name() string { return "file" }
+
+func TestRegistry_TOMLEmbedded(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+[output]
+type = "file"
+name = "main"
+path = "/tmp/out"
+`)
+	defer os.Remove(path)
+
+	var cfg registryConfig
+	err := Load(path, &cfg, toml.Unmarshal, Registry{
+		Types: map[string]interface{}{"file": registryFile{}},
+	})
+	test.NoError(err)
+	test.Equal(
+		registryFile{
+			EmbeddedBase: EmbeddedBase{Name: "main", Port: 80},
+			Path:         "/tmp/out",
+		},
+		cfg.Output,
+	)
+}
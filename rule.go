@@ -0,0 +1,659 @@
+package ko
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Rule is an option for Load method which adds a validation rule
+	// evaluated on the loaded config, e.g. ko.Rule("pool.min <= pool.max").
+	//
+	// Rules are expressions over field paths written the same way as in ko
+	// errors. They support number, string, boolean and duration (30s, 1.5h)
+	// literals, null, arithmetic (+ - * / %), comparisons (== != < <= > >=),
+	// logical operators (&& || !), parentheses and len(path) for strings,
+	// slices and maps. Durations are compared as numbers of nanoseconds.
+	//
+	// The same expressions can be attached to a struct with a blank field
+	// tagged with rule, in which case paths are relative to the struct:
+	//
+	//	type Pool struct {
+	//	    _   struct{} `rule:"min <= max"`
+	//	    Min int      `yaml:"min"`
+	//	    Max int      `yaml:"max"`
+	//	}
+	Rule string
+)
+
+// validateRules evaluates rule tags of blank fields of the struct.
+func validateRules(
+	resource reflect.Value,
+	prefix []string,
+	promote bool,
+) error {
+	resourceStruct := resource.Type()
+	for index := 0; index < resourceStruct.NumField(); index++ {
+		structField := resourceStruct.Field(index)
+		if structField.Name != "_" {
+			continue
+		}
+
+		rule := structField.Tag.Get("rule")
+		if rule == "" {
+			continue
+		}
+
+		err := evaluateRule(rule, resource, prefix, promote)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// evaluateRule checks that rule evaluates to true for resource. Paths in the
+// rule are relative to resource, which is located at prefix; promote tells
+// whether fields of embedded structs are promoted, see isInline.
+func evaluateRule(
+	rule string,
+	resource reflect.Value,
+	prefix []string,
+	promote bool,
+) error {
+	parser := &ruleParser{input: rule}
+
+	node, err := parser.parse()
+	if err != nil {
+		return fmt.Errorf("invalid rule %q: %s", rule, err)
+	}
+
+	scope := &ruleScope{resource: resource, prefix: prefix, promote: promote}
+
+	result, err := node.evaluate(scope)
+	if err != nil {
+		return fmt.Errorf("unable to evaluate rule %q: %s", rule, err)
+	}
+
+	satisfied, ok := result.(bool)
+	if !ok {
+		return fmt.Errorf(
+			"unable to evaluate rule %q: result is not a boolean",
+			rule,
+		)
+	}
+
+	if satisfied {
+		return nil
+	}
+
+	values := []string{}
+	for _, field := range scope.fields {
+		values = append(values, fmt.Sprintf("%q is %s", field.path, field.value))
+	}
+
+	path := prefix
+	if len(prefix) == 0 && len(scope.fields) > 0 {
+		path = []string{scope.fields[0].path}
+	}
+
+	message := fmt.Sprintf("rule %q is not satisfied", rule)
+	if len(values) > 0 {
+		message += ": " + strings.Join(values, ", ")
+	}
+
+	return newFieldError(path, fmt.Errorf("%s", message))
+}
+
+type ruleScope struct {
+	resource reflect.Value
+	prefix   []string
+	promote  bool
+
+	// fields contains paths and values of fields used in the rule, in order
+	// of appearance, for error messages.
+	fields []ruleField
+}
+
+type ruleField struct {
+	path  string
+	value string
+}
+
+// lookup finds the field at path relative to the scope and converts its
+// value to one of the types rules operate on: float64, string, bool, nil or,
+// for slices and maps, reflect.Value.
+func (scope *ruleScope) lookup(path string) (interface{}, error) {
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	value := scope.resource
+	for _, segment := range segments {
+		for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return nil, fmt.Errorf("field %q is nil", path)
+			}
+
+			value = value.Elem()
+		}
+
+		switch {
+		case !segment.item && value.Kind() == reflect.Struct:
+			field, ok := findField(value.Type(), segment.key, scope.promote)
+			if !ok {
+				return nil, fmt.Errorf("unknown field %q", path)
+			}
+
+			value, err = value.FieldByIndexErr(field.Index)
+			if err != nil {
+				return nil, fmt.Errorf("field %q is nil", path)
+			}
+
+		case segment.item && value.Kind() == reflect.Map:
+			key := reflect.New(value.Type().Key()).Elem()
+			err := yaml.Unmarshal([]byte(segment.key), key.Addr().Interface())
+			if err != nil {
+				return nil, fmt.Errorf("invalid key in %q: %s", path, err)
+			}
+
+			value = value.MapIndex(key)
+			if !value.IsValid() {
+				return nil, fmt.Errorf("no item %q in %q", segment.key, path)
+			}
+
+		case segment.item &&
+			(value.Kind() == reflect.Slice || value.Kind() == reflect.Array):
+			index, err := strconv.Atoi(segment.key)
+			if err != nil || index < 0 || index >= value.Len() {
+				return nil, fmt.Errorf("no item %q in %q", segment.key, path)
+			}
+
+			value = value.Index(index)
+
+		default:
+			return nil, fmt.Errorf("unknown field %q", path)
+		}
+	}
+
+	full := strings.Join(push(scope.prefix, path), ".")
+	if len(scope.prefix) > 0 && strings.HasPrefix(path, "[") {
+		full = strings.Join(scope.prefix, ".") + path
+	}
+
+	field := ruleField{path: full, value: "null"}
+	if value.Kind() != reflect.Ptr || !value.IsNil() {
+		field.value = fmt.Sprintf("%v", reflect.Indirect(value).Interface())
+		if reflect.Indirect(value).Kind() == reflect.String {
+			field.value = strconv.Quote(field.value)
+		}
+	}
+
+	scope.fields = append(scope.fields, field)
+
+	return ruleValue(value), nil
+}
+
+func ruleValue(value reflect.Value) interface{} {
+	for value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+
+		value = value.Elem()
+	}
+
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64:
+		return float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	case reflect.String:
+		return value.String()
+	case reflect.Bool:
+		return value.Bool()
+	}
+
+	return value
+}
+
+type ruleNode interface {
+	evaluate(scope *ruleScope) (interface{}, error)
+}
+
+type (
+	ruleLiteral struct {
+		value interface{}
+	}
+
+	rulePath struct {
+		path string
+	}
+
+	ruleUnary struct {
+		operator string
+		operand  ruleNode
+	}
+
+	ruleBinary struct {
+		operator string
+		left     ruleNode
+		right    ruleNode
+	}
+
+	ruleLen struct {
+		argument ruleNode
+	}
+)
+
+func (node ruleLiteral) evaluate(*ruleScope) (interface{}, error) {
+	return node.value, nil
+}
+
+func (node rulePath) evaluate(scope *ruleScope) (interface{}, error) {
+	return scope.lookup(node.path)
+}
+
+func (node ruleUnary) evaluate(scope *ruleScope) (interface{}, error) {
+	value, err := node.operand.evaluate(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node.operator {
+	case "!":
+		if value, ok := value.(bool); ok {
+			return !value, nil
+		}
+	case "-":
+		if value, ok := value.(float64); ok {
+			return -value, nil
+		}
+	}
+
+	return nil, fmt.Errorf("operator %s is not applicable to %v", node.operator, value)
+}
+
+func (node ruleBinary) evaluate(scope *ruleScope) (interface{}, error) {
+	left, err := node.left.evaluate(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if node.operator == "&&" || node.operator == "||" {
+		condition, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s expects booleans", node.operator)
+		}
+
+		if condition == (node.operator == "||") {
+			return condition, nil
+		}
+
+		right, err := node.right.evaluate(scope)
+		if err != nil {
+			return nil, err
+		}
+
+		condition, ok = right.(bool)
+		if !ok {
+			return nil, fmt.Errorf("operator %s expects booleans", node.operator)
+		}
+
+		return condition, nil
+	}
+
+	right, err := node.right.evaluate(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	switch node.operator {
+	case "==":
+		return ruleEqual(left, right), nil
+	case "!=":
+		return !ruleEqual(left, right), nil
+	}
+
+	leftNumber, leftIsNumber := left.(float64)
+	rightNumber, rightIsNumber := right.(float64)
+	if leftIsNumber && rightIsNumber {
+		switch node.operator {
+		case "<":
+			return leftNumber < rightNumber, nil
+		case "<=":
+			return leftNumber <= rightNumber, nil
+		case ">":
+			return leftNumber > rightNumber, nil
+		case ">=":
+			return leftNumber >= rightNumber, nil
+		case "+":
+			return leftNumber + rightNumber, nil
+		case "-":
+			return leftNumber - rightNumber, nil
+		case "*":
+			return leftNumber * rightNumber, nil
+		case "/":
+			if rightNumber == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return leftNumber / rightNumber, nil
+		case "%":
+			if int64(rightNumber) == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return float64(int64(leftNumber) % int64(rightNumber)), nil
+		}
+	}
+
+	leftString, leftIsString := left.(string)
+	rightString, rightIsString := right.(string)
+	if leftIsString && rightIsString {
+		switch node.operator {
+		case "<":
+			return leftString < rightString, nil
+		case "<=":
+			return leftString <= rightString, nil
+		case ">":
+			return leftString > rightString, nil
+		case ">=":
+			return leftString >= rightString, nil
+		case "+":
+			return leftString + rightString, nil
+		}
+	}
+
+	return nil, fmt.Errorf(
+		"operator %s is not applicable to %v and %v",
+		node.operator,
+		left,
+		right,
+	)
+}
+
+func (node ruleLen) evaluate(scope *ruleScope) (interface{}, error) {
+	value, err := node.argument.evaluate(scope)
+	if err != nil {
+		return nil, err
+	}
+
+	switch value := value.(type) {
+	case string:
+		return float64(len(value)), nil
+	case reflect.Value:
+		switch value.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return float64(value.Len()), nil
+		}
+	case nil:
+		return float64(0), nil
+	}
+
+	return nil, fmt.Errorf("len is not applicable to %v", value)
+}
+
+func ruleEqual(left, right interface{}) bool {
+	if left == nil {
+		left, right = right, left
+	}
+
+	if left, ok := left.(reflect.Value); ok {
+		if right == nil {
+			return isNil(left)
+		}
+	}
+
+	return left == right
+}
+
+// isNil reports whether the value is nil, values of kinds which can not be
+// nil, like structs, are never nil.
+func isNil(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface,
+		reflect.Chan, reflect.Func:
+		return value.IsNil()
+	}
+
+	return !value.IsValid()
+}
+
+var rulePrecedence = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+}
+
+type ruleToken struct {
+	kind  string // "number", "string", "path", "operator" or "end"
+	text  string
+	value interface{}
+}
+
+type ruleParser struct {
+	input string
+	token ruleToken
+}
+
+func (parser *ruleParser) parse() (ruleNode, error) {
+	err := parser.next()
+	if err != nil {
+		return nil, err
+	}
+
+	node, err := parser.parseBinary(1)
+	if err != nil {
+		return nil, err
+	}
+
+	if parser.token.kind != "end" {
+		return nil, fmt.Errorf("unexpected %q", parser.token.text)
+	}
+
+	return node, nil
+}
+
+func (parser *ruleParser) parseBinary(precedence int) (ruleNode, error) {
+	left, err := parser.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		operator := parser.token.text
+		current, ok := rulePrecedence[operator]
+		if parser.token.kind != "operator" || !ok || current < precedence {
+			return left, nil
+		}
+
+		err := parser.next()
+		if err != nil {
+			return nil, err
+		}
+
+		right, err := parser.parseBinary(current + 1)
+		if err != nil {
+			return nil, err
+		}
+
+		left = ruleBinary{operator: operator, left: left, right: right}
+	}
+}
+
+func (parser *ruleParser) parseUnary() (ruleNode, error) {
+	token := parser.token
+
+	err := parser.next()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case token.kind == "operator" && (token.text == "!" || token.text == "-"):
+		operand, err := parser.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return ruleUnary{operator: token.text, operand: operand}, nil
+
+	case token.kind == "operator" && token.text == "(":
+		node, err := parser.parseBinary(1)
+		if err != nil {
+			return nil, err
+		}
+
+		return node, parser.expect(")")
+
+	case token.kind == "number" || token.kind == "string":
+		return ruleLiteral{value: token.value}, nil
+
+	case token.kind == "path":
+		switch token.text {
+		case "true":
+			return ruleLiteral{value: true}, nil
+		case "false":
+			return ruleLiteral{value: false}, nil
+		case "null":
+			return ruleLiteral{value: nil}, nil
+		case "len":
+			if parser.token.text != "(" {
+				break
+			}
+
+			err := parser.expect("(")
+			if err != nil {
+				return nil, err
+			}
+
+			argument, err := parser.parseBinary(1)
+			if err != nil {
+				return nil, err
+			}
+
+			return ruleLen{argument: argument}, parser.expect(")")
+		}
+
+		return rulePath{path: token.text}, nil
+	}
+
+	if token.kind == "end" {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	return nil, fmt.Errorf("unexpected %q", token.text)
+}
+
+func (parser *ruleParser) expect(text string) error {
+	if parser.token.kind != "operator" || parser.token.text != text {
+		return fmt.Errorf("expected %q", text)
+	}
+
+	return parser.next()
+}
+
+// next reads the next token from input.
+func (parser *ruleParser) next() error {
+	parser.input = strings.TrimLeft(parser.input, " \t\n")
+	input := parser.input
+
+	if input == "" {
+		parser.token = ruleToken{kind: "end"}
+		return nil
+	}
+
+	for _, operator := range []string{
+		"||", "&&", "==", "!=", "<=", ">=",
+		"<", ">", "+", "-", "*", "/", "%", "!", "(", ")",
+	} {
+		if strings.HasPrefix(input, operator) {
+			parser.token = ruleToken{kind: "operator", text: operator}
+			parser.input = input[len(operator):]
+			return nil
+		}
+	}
+
+	char := input[0]
+	switch {
+	case char == '"' || char == '\'':
+		end := strings.IndexByte(input[1:], char)
+		if end < 0 {
+			return fmt.Errorf("unterminated string")
+		}
+
+		text := input[:end+2]
+		parser.token = ruleToken{kind: "string", text: text, value: text[1 : end+1]}
+		parser.input = input[end+2:]
+
+		return nil
+
+	case char >= '0' && char <= '9' || char == '.':
+		end := strings.IndexFunc(input, func(char rune) bool {
+			return !(char >= '0' && char <= '9' || char == '.' ||
+				char >= 'a' && char <= 'z' || char == 'µ')
+		})
+		if end < 0 {
+			end = len(input)
+		}
+
+		text := input[:end]
+		parser.input = input[end:]
+
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			duration, err := time.ParseDuration(text)
+			if err != nil {
+				return fmt.Errorf("invalid number %q", text)
+			}
+
+			number = float64(duration)
+		}
+
+		parser.token = ruleToken{kind: "number", text: text, value: number}
+
+		return nil
+
+	case char == '_' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z':
+		end := 0
+		for end < len(input) {
+			char := input[end]
+			if char == '[' {
+				close := strings.IndexByte(input[end:], ']')
+				if close < 0 {
+					return fmt.Errorf("unclosed bracket")
+				}
+
+				end += close + 1
+				continue
+			}
+
+			if !(char == '_' || char == '.' || char >= 'a' && char <= 'z' ||
+				char >= 'A' && char <= 'Z' || char >= '0' && char <= '9') {
+				break
+			}
+
+			end++
+		}
+
+		parser.token = ruleToken{kind: "path", text: input[:end]}
+		parser.input = input[end:]
+
+		return nil
+	}
+
+	return fmt.Errorf("unexpected %q", string(char))
+}
@@ -0,0 +1,189 @@
+package ko
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type ruleConfig struct {
+	Pool struct {
+		_   struct{} `rule:"min <= max"`
+		Min int      `yaml:"min"`
+		Max int      `yaml:"max"`
+	} `yaml:"pool"`
+
+	Timeout   time.Duration `yaml:"timeout"`
+	KeepAlive time.Duration `yaml:"keepalive"`
+
+	Name  string   `yaml:"name"`
+	Hosts []string `yaml:"hosts"`
+}
+
+func TestRule_Tag(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`pool: {min: 1, max: 2}`)
+	defer os.Remove(path)
+
+	var cfg ruleConfig
+	test.NoError(Load(path, &cfg, yaml.Unmarshal))
+
+	path = write(`pool: {min: 3, max: 2}`)
+	defer os.Remove(path)
+
+	test.EqualError(
+		Load(path, &ruleConfig{}, yaml.Unmarshal),
+		`rule "min <= max" is not satisfied: "pool.min" is 3, "pool.max" is 2`,
+	)
+}
+
+func TestRule_Durations(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`{timeout: 30s, keepalive: 10s}`)
+	defer os.Remove(path)
+
+	var cfg ruleConfig
+	test.NoError(Load(path, &cfg, yaml.Unmarshal, Rule("timeout > keepalive")))
+
+	path = write(`{timeout: 5s, keepalive: 1s}`)
+	defer os.Remove(path)
+
+	test.NoError(
+		Load(path, &ruleConfig{}, yaml.Unmarshal, Rule("timeout >= 1s")),
+	)
+	test.EqualError(
+		Load(path, &ruleConfig{}, yaml.Unmarshal,
+			Rule("timeout >= 1m || keepalive == 0"),
+		),
+		`rule "timeout >= 1m || keepalive == 0" is not satisfied: `+
+			`"timeout" is 5s, "keepalive" is 1s`,
+	)
+}
+
+func TestRule_Expressions(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`{name: main, hosts: [a, b], pool: {min: 1, max: 4}}`)
+	defer os.Remove(path)
+
+	var cfg ruleConfig
+	err := Load(path, &cfg, yaml.Unmarshal,
+		Rule("(pool.max - pool.min) * 2 >= 6 && !(pool.max % 2 == 1)"),
+		Rule(`name != "" && len(hosts) >= 2 && hosts[0] == 'a'`),
+	)
+	test.NoError(err)
+
+	test.EqualError(
+		Load(path, &ruleConfig{}, yaml.Unmarshal, Rule(`len(hosts) > 2`)),
+		`rule "len(hosts) > 2" is not satisfied: "hosts" is [a b]`,
+	)
+}
+
+func TestRule_Invalid(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(``)
+	defer os.Remove(path)
+
+	var cfg ruleConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal, Rule(`pool.size > 0`)),
+		`unable to evaluate rule "pool.size > 0": unknown field "pool.size"`,
+	)
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal, Rule(`pool.min <=`)),
+		`invalid rule "pool.min <=": unexpected end of expression`,
+	)
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal, Rule(`pool.min + 1`)),
+		`unable to evaluate rule "pool.min + 1": result is not a boolean`,
+	)
+}
+
+func TestRule_FieldError(t *testing.T) {
+	test := assert.New(t)
+
+	path := write("timeout: 1s\nkeepalive: 2s\n")
+	defer os.Remove(path)
+
+	var cfg ruleConfig
+	err := Load(path, &cfg, yaml.Unmarshal, Rule("timeout > keepalive"))
+
+	var fieldError *FieldError
+	test.True(errors.As(err, &fieldError))
+	test.Equal("timeout", fieldError.Path)
+	test.Equal(1, fieldError.Position.Line)
+}
+
+func TestRule_Items(t *testing.T) {
+	test := assert.New(t)
+
+	type route struct {
+		_    struct{} `rule:"len(path) > 1"`
+		Path string   `yaml:"path"`
+	}
+
+	var cfg struct {
+		Routes map[string]route `yaml:"routes"`
+	}
+
+	path := write("routes: {main: {path: /api}, root: {path: /}}")
+	defer os.Remove(path)
+
+	err := Load(
+		path, &cfg, yaml.Unmarshal, Rule(`routes[main].path == "/api"`),
+	)
+	test.EqualError(
+		err,
+		`rule "len(path) > 1" is not satisfied: "routes[root].path" is "/"`,
+	)
+
+	var fieldError *FieldError
+	test.True(errors.As(err, &fieldError))
+	test.Equal("routes[root]", fieldError.Path)
+}
+
+func TestRule_NullStruct(t *testing.T) {
+	test := assert.New(t)
+
+	type tlsConfig struct {
+		Cert string `yaml:"cert"`
+	}
+
+	type config struct {
+		TLS  *tlsConfig `yaml:"tls"`
+		Pool struct {
+			Size int `yaml:"size"`
+		} `yaml:"pool"`
+	}
+
+	path := write(`{tls: {cert: a}, pool: {size: 1}}`)
+	defer os.Remove(path)
+
+	// structs are never null, comparing them with null must not panic
+	var cfg config
+	err := Load(path, &cfg, yaml.Unmarshal,
+		Rule(`tls == null || tls.cert != ""`),
+		Rule(`null != tls`),
+		Rule(`pool != null`),
+	)
+	test.NoError(err)
+
+	test.EqualError(
+		Load(path, &config{}, yaml.Unmarshal, Rule(`pool == null`)),
+		`rule "pool == null" is not satisfied: "pool" is {1}`,
+	)
+
+	path = write(`{}`)
+	defer os.Remove(path)
+
+	test.NoError(
+		Load(path, &config{}, yaml.Unmarshal, Rule(`tls == null || tls.cert != ""`)),
+	)
+}
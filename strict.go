@@ -0,0 +1,345 @@
+package ko
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Strict is an option for Load method which makes Load fail if the
+	// configuration contains keys that do not map to any struct field.
+	Strict bool
+)
+
+// directiveKeys are top-level keys handled by ko itself rather than mapped to
+// the resource.
+var directiveKeys = []string{"extends", "include", "profiles"}
+
+type unknownKey struct {
+	path       string
+	suggestion string
+}
+
+// checkUnknownKeys decodes every loaded document into generic data and
+// reports all keys that have no corresponding field in resource, including
+// keys inside profile sections.
+func (loader *loader) checkUnknownKeys(resource interface{}) error {
+	resourceType := reflect.TypeOf(resource)
+	promote := loader.promotesEmbedded()
+
+	found := map[string]unknownKey{}
+	for _, file := range loader.files {
+		var document interface{}
+		err := loader.unmarshaller(file.data, &document)
+		if err != nil {
+			return err
+		}
+
+		keys := []unknownKey{}
+		if mapping, ok := document.(map[string]interface{}); ok {
+			if profiles, ok := mapping["profiles"].(map[string]interface{}); ok {
+				for name, profile := range profiles {
+					keys = append(
+						keys,
+						findUnknownKeys(
+							resourceType,
+							profile,
+							promote,
+							false,
+							collectAliases(resourceType, promote),
+							nil,
+							"profiles."+name,
+						)...,
+					)
+				}
+			}
+		}
+
+		keys = append(
+			keys,
+			findUnknownKeys(
+				resourceType,
+				document,
+				promote,
+				true,
+				collectAliases(resourceType, promote),
+				nil,
+			)...,
+		)
+		for _, key := range keys {
+			found[key.path] = key
+		}
+	}
+
+	if len(found) == 0 {
+		return nil
+	}
+
+	paths := []string{}
+	for path := range found {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	errs := []error{}
+	for _, path := range paths {
+		key := found[path]
+		err := fmt.Errorf("unknown key %q", key.path)
+		if key.suggestion != "" {
+			err = fmt.Errorf(
+				"unknown key %q, did you mean %q?",
+				key.path,
+				key.suggestion,
+			)
+		}
+
+		errs = append(errs, &FieldError{Path: key.path, Err: err})
+	}
+
+	return errors.Join(errs...)
+}
+
+// findUnknownKeys walks generic data decoded from a document along with the
+// type it is unmarshalled into and returns keys that do not match any field.
+// Keys listed in aliases, which are relative to the document root or to the
+// current slice or map item, are known too; itemPath is the path of data
+// relative to the same root.
+func findUnknownKeys(
+	target reflect.Type,
+	data interface{},
+	promote bool,
+	root bool,
+	aliases []string,
+	itemPath []string,
+	prefix ...string,
+) []unknownKey {
+	for target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+
+	if data == nil || hasCustomUnmarshaller(target) {
+		return nil
+	}
+
+	value := reflect.ValueOf(data)
+	keys := []unknownKey{}
+
+	switch target.Kind() {
+	case reflect.Struct:
+		mapping, ok := data.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+
+		for key, item := range mapping {
+			if root && inSlice(directiveKeys, key) {
+				continue
+			}
+
+			field, ok := matchField(target, key, promote)
+			if !ok && isAliasKey(aliases, push(itemPath, key)) {
+				continue
+			}
+
+			if !ok {
+				unknown := unknownKey{
+					path: strings.Join(push(prefix, key), "."),
+				}
+
+				suggestion := suggestKey(target, key, promote)
+				if suggestion != "" {
+					unknown.suggestion = strings.Join(
+						push(prefix, suggestion),
+						".",
+					)
+				}
+
+				keys = append(keys, unknown)
+				continue
+			}
+
+			keys = append(
+				keys,
+				findUnknownKeys(
+					field.Type,
+					item,
+					promote,
+					false,
+					aliases,
+					push(itemPath, key),
+					push(prefix, getFieldKey(field))...,
+				)...,
+			)
+		}
+
+	case reflect.Map:
+		if value.Kind() != reflect.Map {
+			return nil
+		}
+
+		for _, key := range value.MapKeys() {
+			keys = append(
+				keys,
+				findUnknownKeys(
+					target.Elem(),
+					value.MapIndex(key).Interface(),
+					promote,
+					false,
+					collectAliases(target.Elem(), promote),
+					nil,
+					pushItem(prefix, fmt.Sprint(key.Interface()))...,
+				)...,
+			)
+		}
+
+	case reflect.Slice, reflect.Array:
+		if value.Kind() != reflect.Slice {
+			return nil
+		}
+
+		for i := 0; i < value.Len(); i++ {
+			keys = append(
+				keys,
+				findUnknownKeys(
+					target.Elem(),
+					value.Index(i).Interface(),
+					promote,
+					false,
+					collectAliases(target.Elem(), promote),
+					nil,
+					pushItem(prefix, fmt.Sprint(i))...,
+				)...,
+			)
+		}
+	}
+
+	return keys
+}
+
+// matchField finds the field of struct type that the key is unmarshalled
+// into. Besides ko's own key name it accepts key names of every supported
+// format; untagged fields are matched case-insensitively by name, because
+// every format names them differently.
+func matchField(
+	target reflect.Type,
+	key string,
+	promote bool,
+) (reflect.StructField, bool) {
+	for _, field := range structFields(target, promote) {
+		if getFieldKey(field) == key {
+			return field, true
+		}
+
+		tagged := false
+		for _, tag := range []string{"yaml", "toml", "json"} {
+			name := strings.Split(field.Tag.Get(tag), ",")[0]
+			if name != "" && name != "-" {
+				tagged = true
+			}
+
+			if name == key {
+				return field, true
+			}
+		}
+
+		if !tagged && strings.EqualFold(field.Name, key) {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// suggestKey returns the key of struct field closest to the given unknown
+// key or an empty string if none of them is close enough.
+func suggestKey(target reflect.Type, key string, promote bool) string {
+	var (
+		suggestion string
+		best       = len(key)/2 + 1
+	)
+
+	for _, field := range structFields(target, promote) {
+		candidate := getFieldKey(field)
+
+		distance := levenshtein(strings.ToLower(key), candidate)
+		if distance < best {
+			best = distance
+			suggestion = candidate
+		}
+	}
+
+	return suggestion
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
+			}
+
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
+			}
+		}
+
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
+
+// hasCustomUnmarshaller reports whether values of the type decode themselves,
+// in which case keys inside them are not ko's business.
+func hasCustomUnmarshaller(target reflect.Type) bool {
+	for _, unmarshaller := range customUnmarshallers {
+		if reflect.PtrTo(target).Implements(unmarshaller) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var customUnmarshallers = []reflect.Type{
+	reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem(),
+	reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem(),
+	reflect.TypeOf((*json.Unmarshaler)(nil)).Elem(),
+	reflect.TypeOf((*toml.Unmarshaler)(nil)).Elem(),
+}
+
+// pushItem appends [item] to the last element of path, so items of slices
+// and maps are named like "routes[1]".
+func pushItem(prefix []string, item string) []string {
+	if len(prefix) == 0 {
+		return []string{"[" + item + "]"}
+	}
+
+	last := len(prefix) - 1
+
+	return push(prefix[:last], prefix[last]+"["+item+"]")
+}
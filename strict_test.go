@@ -0,0 +1,131 @@
+package ko
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type strictConfig struct {
+	Listen string `yaml:"listen" toml:"listen" json:"listen" default:":80"`
+	DB     struct {
+		Host    string `yaml:"host" toml:"host" json:"host"`
+		MaxOpen int    `yaml:"max_open" toml:"max_open" json:"max_open"`
+	} `yaml:"db" toml:"db" json:"db"`
+	Routes []struct {
+		Path string `yaml:"path" toml:"path" json:"path"`
+	} `yaml:"routes" toml:"routes" json:"routes"`
+	Workers map[string]struct {
+		Threads int `yaml:"threads" toml:"threads" json:"threads"`
+	} `yaml:"workers" toml:"workers" json:"workers"`
+	Timeout time.Time `yaml:"timeout" toml:"timeout" json:"timeout"`
+	Debug   bool
+}
+
+func TestStrict_Disabled(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`listne = ":8080"`)
+	defer os.Remove(path)
+
+	var cfg strictConfig
+	test.NoError(Load(path, &cfg))
+	test.Equal(":80", cfg.Listen)
+}
+
+func TestStrict_TOML(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+listne = ":8080"
+debug = true
+
+[db]
+hots = "localhost"
+max_open = 10
+
+[[routes]]
+path = "/"
+backend = "web"
+
+[workers.eu]
+thread = 4
+`)
+	defer os.Remove(path)
+
+	var cfg strictConfig
+	test.EqualError(
+		Load(path, &cfg, Strict(true)),
+		`unknown key "db.hots", did you mean "db.host"?`+"\n"+
+			`unknown key "listne", did you mean "listen"?`+"\n"+
+			`unknown key "routes[0].backend"`+"\n"+
+			`unknown key "workers[eu].thread", did you mean "workers[eu].threads"?`,
+	)
+}
+
+func TestStrict_YAML(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+listen: ":8080"
+db:
+  host: localhost
+  maxopen: 10
+routes:
+  - path: /
+`)
+	defer os.Remove(path)
+
+	var cfg strictConfig
+	test.EqualError(
+		Load(path, &cfg, yaml.Unmarshal, Strict(true)),
+		`unknown key "db.maxopen", did you mean "db.max_open"?`,
+	)
+}
+
+func TestStrict_JSON(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`{"listen": ":8080", "Debug": true, "timeout": "2020-01-01T00:00:00Z"}`)
+	defer os.Remove(path)
+
+	var cfg strictConfig
+	test.NoError(Load(path, &cfg, json.Unmarshal, Strict(true)))
+	test.True(cfg.Debug)
+}
+
+func TestStrict_DirectivesAndProfiles(t *testing.T) {
+	test := assert.New(t)
+
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": `
+listen: ":80"
+`,
+		"app.yaml": `
+extends: base.yaml
+include: [db.yaml]
+profiles:
+  prod:
+    listen: ":443"
+    debgu: true
+`,
+		"db.yaml": `
+db:
+  host: localhost
+`,
+	})
+
+	var cfg strictConfig
+	test.EqualError(
+		Load(
+			filepath.Join(dir, "app.yaml"), &cfg,
+			yaml.Unmarshal, Strict(true),
+		),
+		`unknown key "profiles.prod.debgu", did you mean "profiles.prod.debug"?`,
+	)
+}
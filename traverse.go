@@ -0,0 +1,70 @@
+package ko
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+type (
+	// MaxDepth is an option for Load method which limits how deep nested
+	// structs are validated; Load fails if the config is nested deeper. It
+	// guards against runaway trees of self-referencing types. Cycles and
+	// pointers shared between several fields are handled without it: every
+	// pointer is processed once.
+	MaxDepth int
+)
+
+// visitKey identifies a value behind a pointer in one of passes over the
+// resource. The type is a part of the key, because a struct and its first
+// field share the address.
+type visitKey struct {
+	pass    string
+	pointer uintptr
+	target  reflect.Type
+}
+
+// visit marks the value behind pointer as visited by the pass and reports
+// whether it was not visited before. Values which are not pointers are
+// always visited.
+func (loader *loader) visit(pass string, value reflect.Value) bool {
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return true
+	}
+
+	key := visitKey{pass: pass, pointer: value.Pointer(), target: value.Type()}
+	if _, ok := loader.visited[key]; ok {
+		return false
+	}
+
+	if loader.visited == nil {
+		loader.visited = map[visitKey]reflect.Value{}
+	}
+
+	// The value is kept to hold the pointer alive: temporary copies, like
+	// those of map values, would be freed otherwise, and values allocated
+	// later at the same address would be taken for visited ones.
+	loader.visited[key] = value
+
+	return true
+}
+
+// enter increases depth of nested structs and fails if it exceeds MaxDepth.
+// Every successful enter must be followed by leave.
+func (loader *loader) enter(prefix []string) error {
+	if loader.maxDepth > 0 && loader.depth >= loader.maxDepth {
+		return newFieldError(prefix, fmt.Errorf(
+			"field %q: maximum depth of %d is exceeded",
+			strings.Join(prefix, "."),
+			loader.maxDepth,
+		))
+	}
+
+	loader.depth++
+
+	return nil
+}
+
+func (loader *loader) leave() {
+	loader.depth--
+}
@@ -0,0 +1,108 @@
+package ko
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type traverseNode struct {
+	Name     string          `yaml:"name" required:"true"`
+	Weight   int             `yaml:"weight" default:"1"`
+	Children []*traverseNode `yaml:"children"`
+	Parent   *traverseNode   `yaml:"-"`
+}
+
+func TestTraverse_Tree(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+name: root
+children:
+  - name: a
+    children: [{name: b}, {name: c, weight: 3}]
+`)
+	defer os.Remove(path)
+
+	var cfg traverseNode
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+	test.Equal(1, cfg.Children[0].Children[0].Weight)
+	test.Equal(3, cfg.Children[0].Children[1].Weight)
+}
+
+func TestTraverse_Cycle(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`name: root`)
+	defer os.Remove(path)
+
+	events := []Event{}
+
+	cfg := &traverseNode{}
+	cfg.Parent = cfg
+	cfg.Children = []*traverseNode{cfg, cfg}
+
+	err := Load(path, cfg, yaml.Unmarshal, func(event Event) {
+		events = append(events, event)
+	})
+	test.NoError(err)
+	test.Equal(1, cfg.Weight)
+
+	// the shared node is processed once
+	test.Equal([]Event{{Kind: EventDefault, Path: "weight", Value: "1"}}, events)
+}
+
+func TestTraverse_MaxDepth(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`
+name: root
+children:
+  - name: a
+    children: [{name: b, children: [{name: c}]}]
+`)
+	defer os.Remove(path)
+
+	err := Load(path, &traverseNode{}, yaml.Unmarshal, MaxDepth(4))
+	test.NoError(err)
+
+	err = Load(path, &traverseNode{}, yaml.Unmarshal, MaxDepth(3))
+	test.EqualError(
+		err,
+		`field "children[0].children[0].children[0]": maximum depth of 3 is exceeded`,
+	)
+}
+
+func TestTraverse_LargeMap(t *testing.T) {
+	test := assert.New(t)
+
+	type service struct {
+		Host string `yaml:"host"`
+		Port int    `yaml:"port" default:"80"`
+	}
+
+	data := strings.Builder{}
+	for i := 0; i < 5000; i++ {
+		fmt.Fprintf(&data, "svc%d: {host: h%d}\n", i, i)
+	}
+
+	path := write(data.String())
+	defer os.Remove(path)
+
+	var cfg map[string]service
+	err := Load(path, &cfg, yaml.Unmarshal)
+	test.NoError(err)
+	test.Len(cfg, 5000)
+
+	// copies of map values must not be taken for visited values
+	for key, value := range cfg {
+		if value.Port != 80 {
+			t.Fatalf("default is not applied to %q", key)
+		}
+	}
+}
@@ -0,0 +1,270 @@
+package ko
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Duration is a time.Duration which is decoded from strings like "30s",
+	// "1h30m" or "7d" in every supported format, env values and default
+	// tags. Besides units of time.ParseDuration it accepts d for 24 hours.
+	// It is encoded back to the same notation.
+	Duration time.Duration
+
+	// ByteSize is a number of bytes which is decoded from strings like
+	// "512MiB", "10MB" or "1.5G". Units are B, K/KB, M/MB, G/GB, T/TB,
+	// P/PB for powers of 1000 and KiB, MiB, GiB, TiB, PiB for powers of
+	// 1024, case-insensitive. A number without unit is a number of bytes.
+	ByteSize uint64
+
+	// Percent is a fraction which is decoded from strings like "75%" as
+	// 0.75. A number without the percent sign is a fraction as is.
+	Percent float64
+)
+
+const day = 24 * time.Hour
+
+// UnmarshalText parses duration with optional leading number of days.
+func (duration *Duration) UnmarshalText(text []byte) error {
+	value := strings.TrimSpace(string(text))
+
+	sign := time.Duration(1)
+	switch {
+	case strings.HasPrefix(value, "-"):
+		sign = -1
+		value = value[1:]
+	case strings.HasPrefix(value, "+"):
+		value = value[1:]
+	}
+
+	var result time.Duration
+
+	if index := strings.IndexByte(value, 'd'); index >= 0 {
+		days, err := strconv.ParseFloat(value[:index], 64)
+		if err != nil || days < 0 {
+			return fmt.Errorf("invalid duration %q", string(text))
+		}
+
+		// MaxInt64 is not representable as float64 and rounds up to 2^63,
+		// which is the first value out of range.
+		nanoseconds := days * float64(day)
+		if nanoseconds >= math.MaxInt64 {
+			return fmt.Errorf("duration %q is out of range", string(text))
+		}
+
+		result = time.Duration(nanoseconds)
+		value = value[index+1:]
+		if value == "" {
+			*duration = Duration(sign * result)
+			return nil
+		}
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil || parsed < 0 {
+		return fmt.Errorf("invalid duration %q", string(text))
+	}
+
+	if parsed > math.MaxInt64-result {
+		return fmt.Errorf("duration %q is out of range", string(text))
+	}
+
+	*duration = Duration(sign * (result + parsed))
+
+	return nil
+}
+
+// UnmarshalJSON accepts strings, which are parsed the same way as text, and
+// numbers of nanoseconds, the way time.Duration is encoded in JSON.
+func (duration *Duration) UnmarshalJSON(data []byte) error {
+	text := strings.TrimSpace(string(data))
+	if strings.HasPrefix(text, `"`) {
+		return duration.UnmarshalText(unquoteJSON(data))
+	}
+
+	nanoseconds, err := strconv.ParseInt(text, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid duration %s", text)
+	}
+
+	*duration = Duration(nanoseconds)
+
+	return nil
+}
+
+func (duration Duration) MarshalText() ([]byte, error) {
+	return []byte(duration.String()), nil
+}
+
+// String formats duration like time.Duration does, but with whole days
+// written as d and without trailing zero units, e.g. "1d12h" or "1m".
+func (duration Duration) String() string {
+	value := time.Duration(duration)
+
+	sign := ""
+	if value < 0 {
+		sign = "-"
+		value = -value
+	}
+
+	days := value / day
+	value -= days * day
+
+	result := ""
+	if days > 0 {
+		result = strconv.FormatInt(int64(days), 10) + "d"
+	}
+
+	if value > 0 || days == 0 {
+		rest := value.String()
+		if strings.HasSuffix(rest, "m0s") {
+			rest = strings.TrimSuffix(rest, "0s")
+		}
+
+		if strings.HasSuffix(rest, "h0m") {
+			rest = strings.TrimSuffix(rest, "0m")
+		}
+
+		result += rest
+	}
+
+	return sign + result
+}
+
+var byteUnits = map[string]uint64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"p":   1e15,
+	"pb":  1e15,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+}
+
+// UnmarshalText parses number of bytes with optional unit.
+func (size *ByteSize) UnmarshalText(text []byte) error {
+	value := strings.TrimSpace(string(text))
+
+	end := strings.IndexFunc(value, func(char rune) bool {
+		return !(char >= '0' && char <= '9' || char == '.')
+	})
+	if end < 0 {
+		end = len(value)
+	}
+
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(value[end:]))]
+	if !ok || end == 0 {
+		return fmt.Errorf("invalid byte size %q", string(text))
+	}
+
+	number, err := strconv.ParseFloat(value[:end], 64)
+	if err != nil {
+		return fmt.Errorf("invalid byte size %q", string(text))
+	}
+
+	// MaxUint64 is not representable as float64 and rounds up to 2^64,
+	// which is the first value out of range.
+	bytes := math.Round(number * float64(unit))
+	if bytes >= math.MaxUint64 {
+		return fmt.Errorf("byte size %q is too large", string(text))
+	}
+
+	*size = ByteSize(bytes)
+
+	return nil
+}
+
+// UnmarshalJSON accepts both strings and numbers, which are parsed the same
+// way as text.
+func (size *ByteSize) UnmarshalJSON(data []byte) error {
+	return size.UnmarshalText(unquoteJSON(data))
+}
+
+func (size ByteSize) MarshalText() ([]byte, error) {
+	return []byte(size.String()), nil
+}
+
+// String formats size with the largest unit that represents it exactly,
+// preferring binary units, e.g. "512MiB" or "10MB".
+func (size ByteSize) String() string {
+	if size == 0 {
+		return "0B"
+	}
+
+	for _, units := range [][]string{
+		{"PiB", "TiB", "GiB", "MiB", "KiB"},
+		{"PB", "TB", "GB", "MB", "KB"},
+	} {
+		for _, unit := range units {
+			multiplier := byteUnits[strings.ToLower(unit)]
+			if uint64(size)%multiplier == 0 {
+				return strconv.FormatUint(uint64(size)/multiplier, 10) + unit
+			}
+		}
+	}
+
+	return strconv.FormatUint(uint64(size), 10) + "B"
+}
+
+// UnmarshalText parses percentage like "75%" or fraction like "0.75".
+func (percent *Percent) UnmarshalText(text []byte) error {
+	value := strings.TrimSpace(string(text))
+
+	divisor := 1.0
+	if strings.HasSuffix(value, "%") {
+		divisor = 100
+		value = strings.TrimSpace(strings.TrimSuffix(value, "%"))
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid percentage %q", string(text))
+	}
+
+	*percent = Percent(number / divisor)
+
+	return nil
+}
+
+// UnmarshalJSON accepts both strings and numbers, which are parsed the same
+// way as text.
+func (percent *Percent) UnmarshalJSON(data []byte) error {
+	return percent.UnmarshalText(unquoteJSON(data))
+}
+
+func (percent Percent) MarshalText() ([]byte, error) {
+	return []byte(percent.String()), nil
+}
+
+// String formats the fraction as percentage, e.g. "75%".
+func (percent Percent) String() string {
+	// Rounding drops float noise like 7.000000000000001.
+	value := math.Round(float64(percent)*100*1e9) / 1e9
+
+	return strconv.FormatFloat(value, 'f', -1, 64) + "%"
+}
+
+// unquoteJSON returns contents of JSON string or the data as is for other
+// JSON values.
+func unquoteJSON(data []byte) []byte {
+	unquoted, err := strconv.Unquote(string(data))
+	if err != nil {
+		return data
+	}
+
+	return []byte(unquoted)
+}
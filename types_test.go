@@ -0,0 +1,211 @@
+package ko
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestDuration(t *testing.T) {
+	tests := []struct {
+		text      string
+		value     time.Duration
+		formatted string
+		err       string
+	}{
+		{text: "30s", value: 30 * time.Second, formatted: "30s"},
+		{text: "1h30m", value: 90 * time.Minute, formatted: "1h30m"},
+		{text: "10m", value: 10 * time.Minute, formatted: "10m"},
+		{text: "7d", value: 7 * day, formatted: "7d"},
+		{text: "1d12h", value: 36 * time.Hour, formatted: "1d12h"},
+		{text: "1.5d", value: 36 * time.Hour, formatted: "1d12h"},
+		{text: "-2d1s", value: -2*day - time.Second, formatted: "-2d1s"},
+		{text: "0", value: 0, formatted: "0s"},
+		{text: "30", err: `invalid duration "30"`},
+		{text: "d", err: `invalid duration "d"`},
+		{text: "1d-1h", err: `invalid duration "1d-1h"`},
+		{text: "200000d", err: `duration "200000d" is out of range`},
+		{text: "106751d24h", err: `duration "106751d24h" is out of range`},
+		{text: "106751d23h", value: 106751*day + 23*time.Hour, formatted: "106751d23h"},
+		{text: "-106751d", value: -106751 * day, formatted: "-106751d"},
+	}
+
+	for _, tt := range tests {
+		test := assert.New(t)
+
+		var duration Duration
+		err := duration.UnmarshalText([]byte(tt.text))
+		if tt.err != "" {
+			test.EqualError(err, tt.err)
+			continue
+		}
+
+		test.NoError(err, tt.text)
+		test.Equal(tt.value, time.Duration(duration), tt.text)
+		test.Equal(tt.formatted, duration.String(), tt.text)
+	}
+}
+
+func TestDuration_JSON(t *testing.T) {
+	test := assert.New(t)
+
+	var value struct {
+		D Duration `json:"d"`
+	}
+
+	test.NoError(json.Unmarshal([]byte(`{"d": "1d"}`), &value))
+	test.Equal(Duration(day), value.D)
+
+	test.NoError(json.Unmarshal([]byte(`{"d": 30000000000}`), &value))
+	test.Equal(Duration(30*time.Second), value.D)
+
+	test.EqualError(
+		json.Unmarshal([]byte(`{"d": 1.5}`), &value),
+		"invalid duration 1.5",
+	)
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		text      string
+		value     uint64
+		formatted string
+		err       string
+	}{
+		{text: "512MiB", value: 512 << 20, formatted: "512MiB"},
+		{text: "10MB", value: 10e6, formatted: "10MB"},
+		{text: "1.5g", value: 1.5e9, formatted: "1500MB"},
+		{text: "2 KiB", value: 2048, formatted: "2KiB"},
+		{text: "1000", value: 1000, formatted: "1KB"},
+		{text: "1001", value: 1001, formatted: "1001B"},
+		{text: "0", value: 0, formatted: "0B"},
+		{text: "10XB", err: `invalid byte size "10XB"`},
+		{text: "MiB", err: `invalid byte size "MiB"`},
+		{text: "20000000PiB", err: `byte size "20000000PiB" is too large`},
+		{text: "18446744073709551616", err: `byte size "18446744073709551616" is too large`},
+		{text: "16383PiB", value: 16383 << 50, formatted: "16383PiB"},
+	}
+
+	for _, tt := range tests {
+		test := assert.New(t)
+
+		var size ByteSize
+		err := size.UnmarshalText([]byte(tt.text))
+		if tt.err != "" {
+			test.EqualError(err, tt.err)
+			continue
+		}
+
+		test.NoError(err, tt.text)
+		test.Equal(tt.value, uint64(size), tt.text)
+		test.Equal(tt.formatted, size.String(), tt.text)
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		text      string
+		value     float64
+		formatted string
+		err       string
+	}{
+		{text: "75%", value: 0.75, formatted: "75%"},
+		{text: "7%", value: 0.07, formatted: "7%"},
+		{text: "12.5 %", value: 0.125, formatted: "12.5%"},
+		{text: "0.3", value: 0.3, formatted: "30%"},
+		{text: "%", err: `invalid percentage "%"`},
+	}
+
+	for _, tt := range tests {
+		test := assert.New(t)
+
+		var percent Percent
+		err := percent.UnmarshalText([]byte(tt.text))
+		if tt.err != "" {
+			test.EqualError(err, tt.err)
+			continue
+		}
+
+		test.NoError(err, tt.text)
+		test.InDelta(tt.value, float64(percent), 1e-12, tt.text)
+		test.Equal(tt.formatted, percent.String(), tt.text)
+	}
+}
+
+type typesConfig struct {
+	Timeout  Duration `yaml:"timeout" toml:"timeout" json:"timeout"`
+	Retain   Duration `yaml:"retain" toml:"retain" json:"retain" default:"7d"`
+	MaxSize  ByteSize `yaml:"max_size" toml:"max_size" json:"max_size"`
+	Buffer   ByteSize `yaml:"buffer" toml:"buffer" json:"buffer" env:"KO_TEST_BUFFER"`
+	Sampling Percent  `yaml:"sampling" toml:"sampling" json:"sampling" default:"10%"`
+}
+
+func TestTypes_Load(t *testing.T) {
+	test := assert.New(t)
+
+	os.Setenv("KO_TEST_BUFFER", "64KiB")
+	defer os.Unsetenv("KO_TEST_BUFFER")
+
+	expected := typesConfig{
+		Timeout:  Duration(90 * time.Second),
+		Retain:   Duration(7 * day),
+		MaxSize:  512 << 20,
+		Buffer:   64 << 10,
+		Sampling: 0.1,
+	}
+
+	path := write(`{timeout: 1m30s, max_size: 512MiB}`)
+	defer os.Remove(path)
+
+	var cfg typesConfig
+	test.NoError(Load(path, &cfg, yaml.Unmarshal))
+	test.Equal(expected, cfg)
+
+	path = write(`{"timeout": "1m30s", "max_size": 536870912}`)
+	defer os.Remove(path)
+
+	cfg = typesConfig{}
+	test.NoError(Load(path, &cfg, json.Unmarshal))
+	test.Equal(expected, cfg)
+
+	path = write("timeout = \"1m30s\"\nmax_size = \"512MiB\"\n")
+	defer os.Remove(path)
+
+	cfg = typesConfig{}
+	test.NoError(Load(path, &cfg, toml.Unmarshal))
+	test.Equal(expected, cfg)
+}
+
+func TestTypes_Marshal(t *testing.T) {
+	test := assert.New(t)
+
+	cfg := typesConfig{
+		Timeout:  Duration(90 * time.Second),
+		Retain:   Duration(36 * time.Hour),
+		MaxSize:  512 << 20,
+		Sampling: 0.75,
+	}
+
+	data, err := yaml.Marshal(cfg)
+	test.NoError(err)
+	test.Equal(
+		"timeout: 1m30s\nretain: 1d12h\nmax_size: 512MiB\nbuffer: 0B\nsampling: 75%\n",
+		string(data),
+	)
+
+	data, err = json.Marshal(cfg)
+	test.NoError(err)
+	test.Equal(
+		`{"timeout":"1m30s","retain":"1d12h","max_size":"512MiB","buffer":"0B","sampling":"75%"}`,
+		string(data),
+	)
+
+	var decoded typesConfig
+	test.NoError(json.Unmarshal(data, &decoded))
+	test.Equal(cfg, decoded)
+}
@@ -0,0 +1,394 @@
+package ko
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/reconquest/karma-go"
+	"gopkg.in/yaml.v3"
+)
+
+// UpdateFile sets values in the YAML file at path and writes it back. See
+// UpdateYAML for details.
+func UpdateFile(
+	path string,
+	resource interface{},
+	values map[string]interface{},
+	opts ...interface{},
+) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	data, err = UpdateYAML(data, resource, values, opts...)
+	if err != nil {
+		return err
+	}
+
+	mode := os.FileMode(0644)
+	if stat, err := os.Stat(path); err == nil {
+		mode = stat.Mode()
+	}
+
+	return ioutil.WriteFile(path, data, mode)
+}
+
+// UpdateYAML sets values in YAML document data without destroying comments
+// and key order. Keys of values are field paths in the same notation ko uses
+// in error messages, e.g. "server.port", "routes[3].backend" or
+// "workers[eu].threads". Paths are resolved against the type of resource,
+// which must be a pointer to the config struct.
+//
+// The updated document is unmarshalled into resource and validated with the
+// same rules as Load, so invalid updates are rejected before anything is
+// returned. opts are the same as for Load; options that control reading of
+// files are ignored.
+func UpdateYAML(
+	data []byte,
+	resource interface{},
+	values map[string]interface{},
+	opts ...interface{},
+) ([]byte, error) {
+	var document yaml.Node
+	err := yaml.Unmarshal(data, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	if document.Kind == 0 {
+		document = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}},
+		}
+	}
+
+	paths := make([]string, 0, len(values))
+	for path := range values {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	resourceType := reflect.TypeOf(resource)
+	for _, path := range paths {
+		keys, err := resolvePath(resourceType, path)
+		if err != nil {
+			return nil, err
+		}
+
+		var value yaml.Node
+		err = value.Encode(values[path])
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to encode value for field %q",
+				path,
+			)
+		}
+
+		err = setNode(document.Content[0], keys, &value)
+		if err != nil {
+			return nil, karma.Format(
+				err,
+				"unable to update field %q",
+				path,
+			)
+		}
+	}
+
+	buffer := bytes.Buffer{}
+	encoder := yaml.NewEncoder(&buffer)
+	encoder.SetIndent(detectIndent(data))
+
+	err = encoder.Encode(&document)
+	if err != nil {
+		return nil, err
+	}
+
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	err = yaml.Unmarshal(buffer.Bytes(), resource)
+	if err != nil {
+		return nil, err
+	}
+
+	loader := newLoader(opts)
+	loader.unmarshaller = yaml.Unmarshal
+
+	err = loader.validate(resource, true)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, rule := range loader.rules {
+		err = evaluateRule(rule, reflect.ValueOf(resource), nil, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// pathKey is a single step of a resolved field path: either a mapping key as
+// it is spelled in the YAML document or an index in a sequence.
+type pathKey struct {
+	key   string
+	index int
+	item  bool
+}
+
+// resolvePath translates field path into keys of YAML document. Struct field
+// names are looked up by getFieldKey, because that is how paths are spelled in
+// ko, and translated to the key yaml.v3 uses for the field. yaml.v3 does not
+// promote fields of embedded structs without the inline flag.
+func resolvePath(target reflect.Type, path string) ([]pathKey, error) {
+	segments, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []pathKey{}
+	for _, segment := range segments {
+		for target.Kind() == reflect.Ptr {
+			target = target.Elem()
+		}
+
+		if !segment.item {
+			if target.Kind() != reflect.Struct {
+				return nil, fmt.Errorf(
+					"field %q: %q is not a struct field",
+					path, segment.key,
+				)
+			}
+
+			field, ok := findField(target, segment.key, false)
+			if !ok {
+				return nil, fmt.Errorf(
+					"field %q: unknown key %q",
+					path, segment.key,
+				)
+			}
+
+			keys = append(keys, pathKey{key: getYAMLKey(field)})
+			target = field.Type
+
+			continue
+		}
+
+		switch target.Kind() {
+		case reflect.Map:
+			keys = append(keys, pathKey{key: segment.key})
+
+		case reflect.Slice, reflect.Array:
+			index, err := strconv.Atoi(segment.key)
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf(
+					"field %q: invalid index %q",
+					path, segment.key,
+				)
+			}
+
+			keys = append(keys, pathKey{index: index, item: true})
+
+		default:
+			return nil, fmt.Errorf(
+				"field %q: %q is not a map or a slice",
+				path, segment.key,
+			)
+		}
+
+		target = target.Elem()
+	}
+
+	return keys, nil
+}
+
+type pathSegment struct {
+	key  string
+	item bool
+}
+
+// splitPath splits path like "routes[3].backend" into segments "routes",
+// [3] and "backend".
+func splitPath(path string) ([]pathSegment, error) {
+	segments := []pathSegment{}
+
+	for len(path) > 0 {
+		if path[0] == '[' {
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed bracket in path %q", path)
+			}
+
+			segments = append(
+				segments,
+				pathSegment{key: path[1:end], item: true},
+			)
+
+			path = strings.TrimPrefix(path[end+1:], ".")
+			continue
+		}
+
+		end := strings.IndexAny(path, ".[")
+		if end < 0 {
+			end = len(path)
+		}
+
+		if end == 0 {
+			return nil, fmt.Errorf("empty key in path %q", path)
+		}
+
+		segments = append(segments, pathSegment{key: path[:end]})
+
+		path = path[end:]
+		if strings.HasPrefix(path, ".") {
+			path = path[1:]
+		}
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("empty path")
+	}
+
+	return segments, nil
+}
+
+// setNode replaces node found by keys with value, creating intermediate
+// mappings if needed. Comments of the replaced node are moved to the new one.
+func setNode(node *yaml.Node, keys []pathKey, value *yaml.Node) error {
+	for len(keys) > 0 {
+		key := keys[0]
+
+		if key.item {
+			if node.Kind != yaml.SequenceNode {
+				return fmt.Errorf("item %d: node is not a sequence", key.index)
+			}
+
+			if key.index >= len(node.Content) {
+				return fmt.Errorf("item %d: index out of range", key.index)
+			}
+
+			if len(keys) == 1 {
+				replaceNode(node.Content[key.index], value)
+				return nil
+			}
+
+			node = node.Content[key.index]
+			keys = keys[1:]
+
+			continue
+		}
+
+		if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+			*node = yaml.Node{
+				Kind:        yaml.MappingNode,
+				Tag:         "!!map",
+				HeadComment: node.HeadComment,
+				LineComment: node.LineComment,
+				FootComment: node.FootComment,
+			}
+		}
+
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("key %q: node is not a mapping", key.key)
+		}
+
+		var found *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key.key {
+				found = node.Content[i+1]
+				break
+			}
+		}
+
+		if found == nil {
+			found = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			node.Content = append(
+				node.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key.key},
+				found,
+			)
+		}
+
+		if len(keys) == 1 {
+			replaceNode(found, value)
+			return nil
+		}
+
+		node = found
+		keys = keys[1:]
+	}
+
+	return nil
+}
+
+func replaceNode(node *yaml.Node, value *yaml.Node) {
+	replacement := *value
+	replacement.HeadComment = node.HeadComment
+	replacement.LineComment = node.LineComment
+	replacement.FootComment = node.FootComment
+
+	if replacement.Kind == node.Kind && replacement.Tag == node.Tag {
+		replacement.Style = node.Style
+	}
+
+	*node = replacement
+}
+
+// detectIndent guesses indentation width of YAML document, so re-encoded
+// document does not differ from the original in every nested line.
+func detectIndent(data []byte) int {
+	indent := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		spaces := len(line) - len(trimmed)
+		if spaces > 0 && (indent == 0 || spaces < indent) {
+			indent = spaces
+		}
+	}
+
+	if indent < 2 {
+		return 2
+	}
+
+	return indent
+}
+
+func findField(
+	resource reflect.Type,
+	key string,
+	promote bool,
+) (reflect.StructField, bool) {
+	for _, field := range structFields(resource, promote) {
+		if getFieldKey(field) == key {
+			return field, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// getYAMLKey returns the key yaml.v3 uses for the struct field.
+func getYAMLKey(field reflect.StructField) string {
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name != "" && name != "-" {
+		return name
+	}
+
+	return strings.ToLower(field.Name)
+}
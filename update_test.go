@@ -0,0 +1,158 @@
+package ko
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type updateConfig struct {
+	Server struct {
+		Host string `yaml:"host" required:"true"`
+		Port int    `yaml:"port" required:"true"`
+	} `yaml:"server" required:"true"`
+
+	Routes []struct {
+		Path    string `yaml:"path" required:"true"`
+		Backend string `yaml:"backend" required:"true"`
+	} `yaml:"routes"`
+
+	Workers map[string]*struct {
+		Threads int `yaml:"threads" default:"1"`
+	} `yaml:"workers"`
+
+	Debug bool `yaml:"debug"`
+}
+
+func TestUpdateYAML_PreservesComments(t *testing.T) {
+	test := assert.New(t)
+
+	data := []byte(`# main server
+server:
+  host: example.com # public name
+  # port to listen on
+  port: 80
+
+routes:
+  - path: /
+    backend: web # default backend
+`)
+
+	var cfg updateConfig
+	result, err := UpdateYAML(data, &cfg, map[string]interface{}{
+		"server.port":       8080,
+		"routes[0].backend": "api",
+	})
+	test.NoError(err)
+
+	test.Equal(`# main server
+server:
+  host: example.com # public name
+  # port to listen on
+  port: 8080
+routes:
+  - path: /
+    backend: api # default backend
+`, string(result))
+
+	test.Equal(8080, cfg.Server.Port)
+	test.Equal("api", cfg.Routes[0].Backend)
+}
+
+func TestUpdateYAML_CreatesMissingKeys(t *testing.T) {
+	test := assert.New(t)
+
+	data := []byte(`server:
+  host: example.com
+  port: 80
+`)
+
+	var cfg updateConfig
+	result, err := UpdateYAML(data, &cfg, map[string]interface{}{
+		"debug":               true,
+		"workers[eu].threads": 4,
+	})
+	test.NoError(err)
+
+	test.Equal(`server:
+  host: example.com
+  port: 80
+debug: true
+workers:
+  eu:
+    threads: 4
+`, string(result))
+
+	test.True(cfg.Debug)
+	test.Equal(4, cfg.Workers["eu"].Threads)
+}
+
+func TestUpdateYAML_UnknownField(t *testing.T) {
+	test := assert.New(t)
+
+	var cfg updateConfig
+	_, err := UpdateYAML([]byte(`server: {host: a, port: 1}`), &cfg,
+		map[string]interface{}{
+			"server.prot": 1,
+		},
+	)
+	test.EqualError(err, `field "server.prot": unknown key "prot"`)
+}
+
+func TestUpdateYAML_Validates(t *testing.T) {
+	test := assert.New(t)
+
+	var cfg updateConfig
+	_, err := UpdateYAML([]byte(`server: {host: a, port: 1}`), &cfg,
+		map[string]interface{}{
+			"server.host": "",
+		},
+	)
+	test.EqualError(
+		err,
+		`field "server.host" is required, but no value specified`,
+	)
+}
+
+func TestUpdateYAML_Rules(t *testing.T) {
+	test := assert.New(t)
+
+	var cfg struct {
+		Min int `yaml:"min"`
+		Max int `yaml:"max"`
+	}
+	_, err := UpdateYAML([]byte("min: 1\nmax: 5\n"), &cfg,
+		map[string]interface{}{
+			"min": 10,
+		},
+		Rule("min <= max"),
+	)
+	test.EqualError(
+		err,
+		`rule "min <= max" is not satisfied: "min" is 10, "max" is 5`,
+	)
+}
+
+func TestUpdateFile(t *testing.T) {
+	test := assert.New(t)
+
+	path := write(`server:
+  host: example.com # keep me
+  port: 80
+`)
+	defer os.Remove(path)
+
+	var cfg updateConfig
+	test.NoError(UpdateFile(path, &cfg, map[string]interface{}{
+		"server.port": 443,
+	}))
+
+	data, err := ioutil.ReadFile(path)
+	test.NoError(err)
+	test.Equal(`server:
+  host: example.com # keep me
+  port: 443
+`, string(data))
+}